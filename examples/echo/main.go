@@ -6,7 +6,6 @@ import (
 	"encoding/hex"
 	"encoding/json"
 	"fmt"
-	"html/template"
 	"io"
 	"net/http"
 	"os"
@@ -26,16 +25,13 @@ import (
 // app is deploy-ready for barefootjs.dev/examples/echo.
 var basePath string
 
-// loadTemplates loads all templates with BarefootJS functions registered
-func loadTemplates() *template.Template {
-	return template.Must(
-		template.New("").Funcs(bf.FuncMap()).ParseGlob("dist/templates/*.tmpl"),
-	)
-}
+// templateGlob matches the compiled component templates.
+const templateGlob = "dist/templates/*.tmpl"
 
 // EchoRenderer adapts bf.Renderer to Echo's Renderer interface.
-// When devMode is true, templates are re-parsed on each request so edits
-// made by `bun run build:watch` show up without restarting the server.
+// The wrapped bf.Renderer holds the template set parsed once in main. When
+// devMode is true, templates are re-parsed on each request so edits made by
+// `bun run build:watch` show up without restarting the server.
 type EchoRenderer struct {
 	bf      *bf.Renderer
 	layout  bf.LayoutFunc
@@ -53,7 +49,7 @@ func (r *EchoRenderer) Render(w io.Writer, name string, data interface{}, c echo
 	opts.ComponentName = name
 	renderer := r.bf
 	if r.devMode {
-		renderer = bf.NewRenderer(loadTemplates(), r.layout)
+		renderer = bf.NewRenderer(bf.MustLoadTemplates(templateGlob), r.layout)
 	}
 	_, err := w.Write([]byte(renderer.Render(opts)))
 	return err
@@ -215,7 +211,7 @@ func main() {
 		}
 	}
 	e.Renderer = &EchoRenderer{
-		bf:      bf.NewRenderer(loadTemplates(), layout),
+		bf:      bf.NewRenderer(bf.MustLoadTemplates(templateGlob), layout),
 		layout:  layout,
 		devMode: devMode,
 	}
@@ -323,7 +319,7 @@ func todosHandler(c echo.Context) error {
 	todoItems := make([]TodoItemProps, len(currentTodos))
 	for i, t := range currentTodos {
 		todoItems[i] = TodoItemProps{
-			ScopeID: fmt.Sprintf("TodoItem_%d", t.ID),
+			ScopeID: bf.ScopeID("TodoItem", t.ID),
 			Todo:    t,
 		}
 	}
@@ -406,7 +402,7 @@ func todosSSRHandler(c echo.Context) error {
 	todoItems := make([]TodoItemProps, len(currentTodos))
 	for i, t := range currentTodos {
 		todoItems[i] = TodoItemProps{
-			ScopeID: fmt.Sprintf("TodoItem_%d", t.ID),
+			ScopeID: bf.ScopeID("TodoItem", t.ID),
 			Todo:    t,
 		}
 	}
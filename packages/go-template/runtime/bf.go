@@ -4,12 +4,26 @@ package bf
 
 import (
 	"bytes"
+	"context"
+	"crypto/sha256"
+	"encoding"
+	"encoding/hex"
 	"encoding/json"
+	"fmt"
 	"html/template"
+	"io"
+	"math"
+	"math/rand"
+	"net/http"
 	"reflect"
+	"regexp"
 	"sort"
 	"strconv"
 	"strings"
+	"sync"
+	"time"
+	"unicode"
+	"unicode/utf8"
 )
 
 // FuncMap returns a template.FuncMap with all BarefootJS helper functions.
@@ -19,39 +33,102 @@ import (
 func FuncMap() template.FuncMap {
 	return template.FuncMap{
 		// Arithmetic
-		"bf_add": Add,
-		"bf_sub": Sub,
-		"bf_mul": Mul,
-		"bf_div": Div,
-		"bf_mod": Mod,
-		"bf_neg": Neg,
+		"bf_add":   Add,
+		"bf_sub":   Sub,
+		"bf_mul":   Mul,
+		"bf_div":   Div,
+		"bf_idiv":  IDiv,
+		"bf_mod":   Mod,
+		"bf_neg":   Neg,
+		"bf_max_n": MaxN,
+		"bf_min_n": MinN,
+
+		// Comparison
+		"bf_eq":  Eq,
+		"bf_ne":  Ne,
+		"bf_gt":  Gt,
+		"bf_lt":  Lt,
+		"bf_gte": Gte,
+		"bf_lte": Lte,
+
+		// Conditional
+		"bf_ternary": Ternary,
+		"bf_cond":    Cond,
+		"bf_truthy":  Truthy,
+		"bf_bool":    ToBool,
+		"bf_and":     And,
+		"bf_or":      Or,
+		"bf_not":     Not,
+
+		// Coercion
+		"bf_number": ToNumber,
+		"bf_string": ToString,
 
 		// String
-		"bf_lower":    Lower,
-		"bf_upper":    Upper,
-		"bf_trim":     Trim,
-		"bf_contains": Contains,
-		"bf_join":     Join,
+		"bf_lower":      Lower,
+		"bf_upper":      Upper,
+		"bf_trim":       Trim,
+		"bf_contains":   Contains,
+		"bf_join":       Join,
+		"bf_join_field": JoinField,
+		"bf_truncate":   Truncate,
+		"bf_pad_start":  PadStart,
+		"bf_pad_end":    PadEnd,
+		"bf_capitalize": Capitalize,
+		"bf_title":      Title,
+
+		// Number Formatting
+		"bf_format_number": FormatNumber,
+		"bf_currency":      Currency,
+		"bf_percent":       Percent,
+
+		// Date/Time Formatting
+		"bf_date": FormatDate,
+		"bf_time": FormatTime,
 
 		// Array/Slice
-		"bf_len":      Len,
-		"bf_at":       At,
-		"bf_includes": Includes,
-		"bf_first":    First,
-		"bf_last":     Last,
+		"bf_len":           Len,
+		"bf_at":            At,
+		"bf_get":           Get,
+		"bf_includes":      Includes,
+		"bf_includes_any":  IncludesAny,
+		"bf_includes_all":  IncludesAll,
+		"bf_index_of":      IndexOf,
+		"bf_last_index_of": LastIndexOf,
+		"bf_first":         First,
+		"bf_last":          Last,
+		"bf_reverse":       Reverse,
+		"bf_chunk":         Chunk,
+		"bf_flatten":       Flatten,
+		"bf_paginate":      Paginate,
+		"bf_slice":         SliceRange,
+		"bf_take":          Take,
+		"bf_drop":          Drop,
+		"bf_range":         Range,
+		"bf_sum":           Sum,
+		"bf_avg":           Avg,
 
 		// Higher-order Array Methods
-		"bf_every":      Every,
-		"bf_some":       Some,
-		"bf_filter":     Filter,
-		"bf_find":       Find,
-		"bf_find_index": FindIndex,
-		"bf_sort":       Sort,
+		"bf_every":            Every,
+		"bf_some":             Some,
+		"bf_every_eq":         EveryEq,
+		"bf_some_eq":          SomeEq,
+		"bf_filter":           Filter,
+		"bf_filter_range":     FilterRange,
+		"bf_find":             Find,
+		"bf_find_index":       FindIndex,
+		"bf_first_incomplete": FirstIncomplete,
+		"bf_sort":             Sort,
+		"bf_sort_values":      SortValues,
+		"bf_group_sums":       GroupSums,
 
 		// Comment marker (for hydration)
 		"bfComment":    Comment,
+		"bfCommentEnd": CommentEnd,
 		"bfTextStart":  TextStart,
 		"bfTextEnd":    TextEnd,
+		"bfListStart":  ListStart,
+		"bfListEnd":    ListEnd,
 
 		// Script collection
 		"bfScripts": BfScripts,
@@ -59,6 +136,9 @@ func FuncMap() template.FuncMap {
 		// Scope attribute value (prepends ~ for child components)
 		"bfScopeAttr": ScopeAttr,
 
+		// List item key attribute (for client-side reconciliation)
+		"bfKey": Key,
+
 		// Child component marker (kept for backward compatibility)
 		"bfIsChild": IsChild,
 
@@ -70,22 +150,140 @@ func FuncMap() template.FuncMap {
 
 		// Scope comment for fragment roots
 		"bfScopeComment": ScopeComment,
+
+		// Post-hydration focus/scroll target
+		"bf_focus_target": FocusTarget,
+
+		// Attribute composition
+		"bf_class":    Class,
+		"bf_attr":     Attr,
+		"bf_attr_val": AttrVal,
+		"bf_style":    Style,
+
+		// JSON data and raw HTML output
+		"bf_data_json": DataJSON,
+		"bf_json":      JSONString,
+		"bf_safe_html": SafeHTML,
+		"bf_nl2br":     NL2BR,
+
+		// Reduced-motion preference hint
+		"bf_motion": Motion,
+
+		// UI markup (see widgets.go)
+		"bf_details":       Details,
+		"bf_tabs":          Tabs,
+		"bf_stepper":       Stepper,
+		"bf_toasts":        ToastRegion,
+		"bf_rating_input":  RatingInput,
+		"bf_load_more":     LoadMoreSentinel,
+		"bf_icon_button":   IconButton,
+		"bf_overlay_theme": OverlayTheme,
+		"bf_checklist":     Checklist,
+		"bf_img_fallback":  ImgWithFallback,
+		"bf_segmented":     Segmented,
+		"bf_goal_bar":      GoalBar,
+		"bf_url":           URL,
+		"bf_sort_header":   SortHeader,
+		"bf_empty_state":   EmptyState,
+	}
+}
+
+// FuncMapNamespaced returns FuncMap with every bf_ helper registered as
+// <prefix>_<name> instead (e.g., "x_add" for "bf_add"), for apps whose own
+// FuncMap already uses names like add or join and that want the helpers under
+// their own namespace. The hydration helpers (bfComment, bfScopeAttr, ...)
+// keep their names.
+//
+// Compiled component templates call the bf_ names, so keep registering
+// FuncMap for them; the two maps can be merged into one template set.
+//
+//	tmpl := template.New("").Funcs(bf.FuncMap()).Funcs(bf.FuncMapNamespaced("x"))
+func FuncMapNamespaced(prefix string) template.FuncMap {
+	fm := FuncMap()
+	out := make(template.FuncMap, len(fm))
+	for name, fn := range fm {
+		if rest, ok := strings.CutPrefix(name, "bf_"); ok {
+			name = prefix + "_" + rest
+		}
+		out[name] = fn
+	}
+	return out
+}
+
+// ScopeID returns the canonical "Name_<key>" scope ID for a component
+// instance, e.g., ScopeID("TodoItem", 3) == "TodoItem_3". The client runtime
+// matches list items to their scopes by this ID, so keys must be stable
+// across renders; ScopeID is deterministic for a given key.
+//
+// key is formatted with fmt.Sprint and encoded losslessly into an
+// attribute-safe token, so distinct keys always give distinct IDs: ASCII
+// letters, digits and '-' are kept, and every other byte (including '_'
+// and each byte of a multibyte rune) is written as _xHH in lowercase hex
+// ("buy milk" becomes "buy_x20milk"). A key of 's' followed only by digits
+// also has its 's' escaped ("s4" becomes "_x734"), so a generated ID never
+// ends in the _s<N> suffix ScopeAttr reads as a child slot. A nil or empty
+// key gives "Name_",
+// which every empty key shares; enable RenderOptions.DetectCollisions to
+// catch such duplicates.
+func ScopeID(componentName string, key any) string {
+	if key == nil {
+		return componentName + "_"
+	}
+	return componentName + "_" + encodeScopeKey(fmt.Sprint(key))
+}
+
+// encodeScopeKey encodes s for ScopeID, keeping [A-Za-z0-9-] and writing
+// all other bytes as _xHH. The leading 's' of a slot-like key (s\d+) is
+// escaped too, so "Name_" + key never matches childSlotPattern.
+func encodeScopeKey(s string) string {
+	const hex = "0123456789abcdef"
+	slotLike := slotLikeKeyPattern.MatchString(s)
+	var b strings.Builder
+	for i := 0; i < len(s); i++ {
+		c := s[i]
+		safe := c == '-' || ('0' <= c && c <= '9') || ('a' <= c && c <= 'z') || ('A' <= c && c <= 'Z')
+		if safe && !(i == 0 && slotLike) {
+			b.WriteByte(c)
+			continue
+		}
+		b.WriteString("_x")
+		b.WriteByte(hex[c>>4])
+		b.WriteByte(hex[c&0xf])
+	}
+	return b.String()
+}
+
+// RootScopeID returns a scope ID with a random 6-character suffix
+// (e.g., "TodoApp_k3x9qa"), the same format generated NewXxxProps
+// constructors use when no ScopeID is given.
+func RootScopeID(componentName string) string {
+	const chars = "abcdefghijklmnopqrstuvwxyz0123456789"
+	b := make([]byte, 6)
+	for i := range b {
+		b[i] = chars[rand.Intn(len(chars))]
 	}
+	return componentName + "_" + string(b)
 }
 
+// slotLikeKeyPattern matches ScopeID keys that would form a child slot
+// suffix after "Name_".
+var slotLikeKeyPattern = regexp.MustCompile(`^s\d+$`)
+
+// childSlotPattern matches the trailing slot suffix of a single child
+// component's scope ID (e.g., "Parent_abc123_s4", "Parent_abc_s12").
+var childSlotPattern = regexp.MustCompile(`_s\d+$`)
+
 // ScopeAttr returns the scope attribute value for bf-s.
 // Returns "~scopeID" for child components (prefixed with ~) and "scopeID" for root components.
-// Checks the BfIsChild field set by Render(), with fallback to scopeID "_sN" pattern.
+// Checks the BfIsChild field set by Render(), with fallback to a trailing "_sN" slot suffix.
 func ScopeAttr(props interface{}) string {
 	scopeID := getStringField(props, "ScopeID")
 	if getBoolField(props, "BfIsChild") {
 		return "~" + scopeID
 	}
-	// Fallback: check scopeID pattern for single child slots (e.g., "Parent_abc123_s4")
-	for i := 0; i < len(scopeID)-2; i++ {
-		if scopeID[i] == '_' && scopeID[i+1] == 's' && scopeID[i+2] >= '0' && scopeID[i+2] <= '9' {
-			return "~" + scopeID
-		}
+	// Fallback: scopeID ends with a slot suffix for single child slots
+	if childSlotPattern.MatchString(scopeID) {
+		return "~" + scopeID
 	}
 	return scopeID
 }
@@ -96,6 +294,12 @@ func IsChild(props interface{}) template.HTMLAttr {
 	return ""
 }
 
+// DebugProps makes BfPropsAttr emit indented JSON so bf-p attributes are
+// readable in devtools when debugging hydration mismatches. Off by default.
+// Enabling it changes the attribute's whitespace, so byte-exact caches or
+// snapshots of rendered output will not match production output.
+var DebugProps bool
+
 // BfPropsAttr returns a bf-p attribute with the JSON-serialized props in flat format.
 // Output format: bf-p='{"propName": value, ...}'
 // Only emits the attribute for root components (BfIsRoot == true).
@@ -106,15 +310,311 @@ func BfPropsAttr(props interface{}) template.HTMLAttr {
 		return ""
 	}
 
-	propsJSON, err := json.Marshal(props)
+	propsJSON, err := MarshalPropsStable(props)
 	if err != nil {
 		return ""
 	}
+	if DebugProps {
+		var indented bytes.Buffer
+		if err := json.Indent(&indented, propsJSON, "", "  "); err == nil {
+			propsJSON = indented.Bytes()
+		}
+	}
 
 	escaped := template.HTMLEscapeString(string(propsJSON))
 	return template.HTMLAttr(`bf-p="` + escaped + `"`)
 }
 
+// internalPropsFields are runtime-injected props fields that are never sent
+// to the client in hydration props, on the root or on any nested child.
+var internalPropsFields = []string{"Scripts", "Portals", "BfIsRoot", "BfIsChild"}
+
+// MarshalPropsStable serializes props for hydration with byte-for-byte
+// deterministic output, so bf-p attributes can be cached and snapshot-tested.
+// Struct fields keep their declaration order and map keys are emitted in
+// sorted order at every nesting level (encoding/json sorts map keys).
+//
+// The runtime-injected Scripts, Portals, BfIsRoot and BfIsChild fields are
+// dropped even when they are not tagged json:"-", as are fields whose bf tag
+// includes the server option, e.g. bf:"server" or bf:"server,default=x"
+// (server-only data such as internal IDs or emails that must not leak to the
+// client). Both apply at every nesting level: in child components held in
+// slices, maps or fields, and in embedded base structs. Values with their own
+// MarshalJSON or MarshalText are emitted as they encode themselves. All other
+// json tag behavior is preserved.
+func MarshalPropsStable(props interface{}) ([]byte, error) {
+	data, err := json.Marshal(props)
+	if err != nil {
+		return nil, err
+	}
+	return filterPropsJSON(data, reflect.ValueOf(props))
+}
+
+var (
+	jsonMarshalerType = reflect.TypeOf((*json.Marshaler)(nil)).Elem()
+	textMarshalerType = reflect.TypeOf((*encoding.TextMarshaler)(nil)).Elem()
+)
+
+// filterPropsJSON walks data, the JSON encoding of v, alongside v and drops
+// the object keys of internalPropsFields and bf:"server" fields from every
+// struct it reaches.
+func filterPropsJSON(data []byte, v reflect.Value) ([]byte, error) {
+	for v.IsValid() && (v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr) {
+		if v.IsNil() || marshalsItself(v) {
+			return data, nil
+		}
+		v = v.Elem()
+	}
+	if !v.IsValid() || marshalsItself(v) {
+		return data, nil
+	}
+
+	switch v.Kind() {
+	case reflect.Struct:
+		fields := jsonFieldsByName(v.Type())
+		return filterJSONObject(data, func(key string) (reflect.Value, bool) {
+			field, ok := fields[key]
+			if !ok {
+				return reflect.Value{}, true
+			}
+			if isInternalPropsField(field.Name) || parseBfTag(field).server {
+				return reflect.Value{}, false
+			}
+			fv, err := v.FieldByIndexErr(field.Index)
+			if err != nil {
+				return reflect.Value{}, true
+			}
+			return fv, true
+		})
+	case reflect.Map:
+		values := make(map[string]reflect.Value, v.Len())
+		iter := v.MapRange()
+		for iter.Next() {
+			if key, ok := jsonMapKey(iter.Key()); ok {
+				values[key] = iter.Value()
+			}
+		}
+		return filterJSONObject(data, func(key string) (reflect.Value, bool) {
+			return values[key], true
+		})
+	case reflect.Slice, reflect.Array:
+		var items []json.RawMessage
+		if len(data) == 0 || data[0] != '[' {
+			return data, nil // e.g. []byte encodes as a base64 string
+		}
+		if err := json.Unmarshal(data, &items); err != nil {
+			return nil, err
+		}
+		var buf bytes.Buffer
+		buf.WriteByte('[')
+		for i, item := range items {
+			if i > 0 {
+				buf.WriteByte(',')
+			}
+			if i < v.Len() {
+				filtered, err := filterPropsJSON(item, v.Index(i))
+				if err != nil {
+					return nil, err
+				}
+				item = filtered
+			}
+			buf.Write(item)
+		}
+		buf.WriteByte(']')
+		return buf.Bytes(), nil
+	}
+	return data, nil
+}
+
+// filterJSONObject rewrites the JSON object data key by key, keeping key
+// order. lookup returns the Go value behind a key to filter recursively (an
+// invalid value leaves it untouched) and false to drop the key.
+func filterJSONObject(data []byte, lookup func(key string) (reflect.Value, bool)) ([]byte, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+	if tok, err := dec.Token(); err != nil || tok != json.Delim('{') {
+		return data, nil
+	}
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	first := true
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+		key, _ := tok.(string)
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		fv, keep := lookup(key)
+		if !keep {
+			continue
+		}
+		if fv.IsValid() {
+			if value, err = filterPropsJSON(value, fv); err != nil {
+				return nil, err
+			}
+		}
+		if !first {
+			buf.WriteByte(',')
+		}
+		first = false
+		encodedKey, _ := json.Marshal(key)
+		buf.Write(encodedKey)
+		buf.WriteByte(':')
+		buf.Write(value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalsItself reports whether encoding/json encodes v through its own
+// MarshalJSON or MarshalText rather than by its fields or elements.
+func marshalsItself(v reflect.Value) bool {
+	t := v.Type()
+	if t.Implements(jsonMarshalerType) || t.Implements(textMarshalerType) {
+		return true
+	}
+	if v.Kind() != reflect.Ptr && v.CanAddr() {
+		pt := reflect.PointerTo(t)
+		return pt.Implements(jsonMarshalerType) || pt.Implements(textMarshalerType)
+	}
+	return false
+}
+
+// isInternalPropsField reports whether name is one of internalPropsFields.
+func isInternalPropsField(name string) bool {
+	for _, internal := range internalPropsFields {
+		if name == internal {
+			return true
+		}
+	}
+	return false
+}
+
+// jsonMapKey returns the object key encoding/json writes for map key k.
+func jsonMapKey(k reflect.Value) (string, bool) {
+	if k.Kind() == reflect.String {
+		return k.String(), true
+	}
+	if tm, ok := k.Interface().(encoding.TextMarshaler); ok {
+		if k.Kind() == reflect.Ptr && k.IsNil() {
+			return "", true
+		}
+		text, err := tm.MarshalText()
+		return string(text), err == nil
+	}
+	switch k.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		return strconv.FormatInt(k.Int(), 10), true
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64, reflect.Uintptr:
+		return strconv.FormatUint(k.Uint(), 10), true
+	}
+	return "", false
+}
+
+// jsonFieldsByName maps each JSON object key encoding/json emits for struct
+// type t to the field behind it, promoting the fields of untagged embedded
+// structs and applying encoding/json's rule for duplicate names: the
+// shallowest field wins, then the only tagged one; otherwise the name is
+// dropped.
+func jsonFieldsByName(t reflect.Type) map[string]reflect.StructField {
+	type candidate struct {
+		field  reflect.StructField
+		depth  int
+		tagged bool
+	}
+	byName := map[string][]candidate{}
+	var walk func(t reflect.Type, index []int, depth int)
+	walk = func(t reflect.Type, index []int, depth int) {
+		for i := 0; i < t.NumField(); i++ {
+			field := t.Field(i)
+			ft := field.Type
+			if ft.Kind() == reflect.Ptr {
+				ft = ft.Elem()
+			}
+			if !field.IsExported() && !(field.Anonymous && ft.Kind() == reflect.Struct) {
+				continue
+			}
+			tag := field.Tag.Get("json")
+			if tag == "-" {
+				continue
+			}
+			name, _, _ := strings.Cut(tag, ",")
+			field.Index = append(append([]int(nil), index...), i)
+			if name == "" && field.Anonymous && ft.Kind() == reflect.Struct {
+				walk(ft, field.Index, depth+1)
+				continue
+			}
+			if !field.IsExported() {
+				continue
+			}
+			tagged := name != ""
+			if !tagged {
+				name = field.Name
+			}
+			byName[name] = append(byName[name], candidate{field, depth, tagged})
+		}
+	}
+	walk(t, nil, 0)
+
+	fields := make(map[string]reflect.StructField, len(byName))
+	for name, cands := range byName {
+		var dominant []candidate
+		for _, c := range cands {
+			switch {
+			case len(dominant) == 0 || c.depth < dominant[0].depth:
+				dominant = []candidate{c}
+			case c.depth == dominant[0].depth:
+				dominant = append(dominant, c)
+			}
+		}
+		var tagged []candidate
+		for _, c := range dominant {
+			if c.tagged {
+				tagged = append(tagged, c)
+			}
+		}
+		switch {
+		case len(dominant) == 1:
+			fields[name] = dominant[0].field
+		case len(tagged) == 1:
+			fields[name] = tagged[0].field
+		}
+	}
+	return fields
+}
+
+// bfTagOptions holds the parsed options of a props field's bf struct tag.
+type bfTagOptions struct {
+	server     bool   // "server": never sent to the client (MarshalPropsStable)
+	hasDefault bool   // "default=..." present (ApplyDefaults)
+	def        string // the default value
+}
+
+// parseBfTag parses the bf struct tag of field. Options are comma-separated,
+// e.g. bf:"server,default=20". "default=" must be the last option and takes
+// the rest of the tag as its value, so a default may itself contain commas.
+// Unknown options are ignored.
+func parseBfTag(field reflect.StructField) bfTagOptions {
+	var opts bfTagOptions
+	tag := field.Tag.Get("bf")
+	for tag != "" {
+		if def, ok := strings.CutPrefix(tag, "default="); ok {
+			opts.hasDefault = true
+			opts.def = def
+			break
+		}
+		opt, rest, _ := strings.Cut(tag, ",")
+		if opt == "server" {
+			opts.server = true
+		}
+		tag = rest
+	}
+	return opts
+}
+
 // =============================================================================
 // Arithmetic Operations
 // =============================================================================
@@ -160,6 +660,30 @@ func Div(a, b any) any {
 	return av / bv
 }
 
+// IDiv returns Math.floor(a / b) as an int, for integer contexts such as
+// array indexing. Integer operands are divided exactly; if either operand
+// is a float the quotient is computed in float64 and then floored, so
+// IDiv(7, 2.5) is 2 and IDiv(-0.5, 1) is -1. Returns 0 if b is 0, like Mod.
+// Div stays float64.
+func IDiv(a, b any) int {
+	if !isIntLike(a) || !isIntLike(b) {
+		bv := toFloat64(b)
+		if bv == 0 {
+			return 0
+		}
+		return int(math.Floor(toFloat64(a) / bv))
+	}
+	av, bv := toInt(a), toInt(b)
+	if bv == 0 {
+		return 0
+	}
+	q := av / bv
+	if av%bv != 0 && (av < 0) != (bv < 0) {
+		q--
+	}
+	return q
+}
+
 // Mod returns a % b (modulo). Supports int only.
 func Mod(a, b any) int {
 	av, bv := toInt(a), toInt(b)
@@ -177,6 +701,205 @@ func Neg(a any) any {
 	return -toFloat64(a)
 }
 
+// MaxN returns the largest of vals. Like Add, the result is an int when all
+// values are int-like, otherwise a float64. Returns 0 when called with no
+// values. Mirrors JavaScript's Math.max(...vals).
+func MaxN(vals ...any) any {
+	return extremum(vals, func(x, best float64) bool { return x > best })
+}
+
+// MinN returns the smallest of vals, following the same rules as MaxN.
+// Mirrors JavaScript's Math.min(...vals).
+func MinN(vals ...any) any {
+	return extremum(vals, func(x, best float64) bool { return x < best })
+}
+
+// extremum returns the value of vals preferred by better, applying the
+// int-preservation rule of Add.
+func extremum(vals []any, better func(x, best float64) bool) any {
+	if len(vals) == 0 {
+		return 0
+	}
+	best := toFloat64(vals[0])
+	allInt := isIntLike(vals[0])
+	for _, v := range vals[1:] {
+		if !isIntLike(v) {
+			allInt = false
+		}
+		if x := toFloat64(v); better(x, best) {
+			best = x
+		}
+	}
+	if allInt && best == float64(int(best)) {
+		return int(best)
+	}
+	return best
+}
+
+// =============================================================================
+// Comparison Operations
+// =============================================================================
+
+// Eq returns a == b. Numbers are compared by value across types (int vs
+// float64), strings by content; other values use reflect.DeepEqual.
+func Eq(a, b any) bool {
+	if as, ok := a.(string); ok {
+		if bs, ok := b.(string); ok {
+			return as == bs
+		}
+	}
+	if isNumeric(a) && isNumeric(b) {
+		return toFloat64(a) == toFloat64(b)
+	}
+	return reflect.DeepEqual(a, b)
+}
+
+// Ne returns a != b using the same coercion as Eq.
+func Ne(a, b any) bool {
+	return !Eq(a, b)
+}
+
+// Gt returns a > b. See compare for coercion rules.
+func Gt(a, b any) bool {
+	return compare(a, b) > 0
+}
+
+// Lt returns a < b. See compare for coercion rules.
+func Lt(a, b any) bool {
+	return compare(a, b) < 0
+}
+
+// Gte returns a >= b. See compare for coercion rules.
+func Gte(a, b any) bool {
+	return compare(a, b) >= 0
+}
+
+// Lte returns a <= b. See compare for coercion rules.
+func Lte(a, b any) bool {
+	return compare(a, b) <= 0
+}
+
+// compare returns -1, 0, or 1 comparing a to b. Two strings compare
+// lexically; anything else is coerced through toFloat64, so an int prop
+// compares correctly against a float64 literal.
+func compare(a, b any) int {
+	if as, ok := a.(string); ok {
+		if bs, ok := b.(string); ok {
+			return strings.Compare(as, bs)
+		}
+	}
+	av, bv := toFloat64(a), toFloat64(b)
+	switch {
+	case av < bv:
+		return -1
+	case av > bv:
+		return 1
+	default:
+		return 0
+	}
+}
+
+// =============================================================================
+// Conditional Operations
+// =============================================================================
+
+// Ternary returns ifTrue when cond is true, otherwise ifFalse.
+// Mirrors JavaScript's cond ? ifTrue : ifFalse. Both arms are already
+// evaluated by the template, so this only selects one.
+func Ternary(cond bool, ifTrue, ifFalse any) any {
+	if cond {
+		return ifTrue
+	}
+	return ifFalse
+}
+
+// Cond is like Ternary but accepts any value as the condition, using
+// JavaScript truthiness (see isTruthy).
+func Cond(cond any, ifTrue, ifFalse any) any {
+	return Ternary(isTruthy(cond), ifTrue, ifFalse)
+}
+
+// Truthy reports whether v is truthy under JavaScript semantics.
+// Mirrors JavaScript's Boolean(v) / !!v.
+func Truthy(v any) bool {
+	return isTruthy(v)
+}
+
+// ToBool converts v to a bool like JavaScript's Boolean(v). It applies the
+// same truthiness as Truthy, And, Or and Not:
+//   - falsy: nil, false, 0 (any numeric type), NaN, "", and nil pointers,
+//     slices, maps, interfaces, funcs and channels
+//   - truthy: everything else, including empty but non-nil slices and maps
+//     (as Boolean([]) and Boolean({}) are true in JavaScript)
+//
+// Use bf_len to test a collection for emptiness.
+func ToBool(v any) bool {
+	return isTruthy(v)
+}
+
+// ToNumber converts v to a float64 like JavaScript's Number(v):
+//   - numbers are returned as-is, true is 1 and false is 0
+//   - strings are trimmed and parsed ("12.5" yields 12.5, "" yields 0)
+//   - anything unparseable yields 0 rather than NaN, so the result is always
+//     safe to feed into bf_add, bf_format_number and friends
+func ToNumber(v any) float64 {
+	switch n := v.(type) {
+	case bool:
+		if n {
+			return 1
+		}
+		return 0
+	case string:
+		f, err := strconv.ParseFloat(strings.TrimSpace(n), 64)
+		if err != nil || math.IsNaN(f) {
+			return 0
+		}
+		return f
+	}
+	return toFloat64(v)
+}
+
+// ToString converts v to a string like JavaScript's String(v) for strings,
+// ints, int64s, float64s and bools (floats use the shortest representation,
+// so 2.0 becomes "2"). Other values, including nil, yield "".
+func ToString(v any) string {
+	return toString(v)
+}
+
+// And returns the first falsy value, or the last value if all are truthy.
+// Mirrors JavaScript's a && b && c. Returns true when called with no values.
+func And(values ...any) any {
+	if len(values) == 0 {
+		return true
+	}
+	for _, v := range values {
+		if !isTruthy(v) {
+			return v
+		}
+	}
+	return values[len(values)-1]
+}
+
+// Or returns the first truthy value, or the last value if none are truthy.
+// Mirrors JavaScript's a || b || c. Returns false when called with no values.
+func Or(values ...any) any {
+	if len(values) == 0 {
+		return false
+	}
+	for _, v := range values {
+		if isTruthy(v) {
+			return v
+		}
+	}
+	return values[len(values)-1]
+}
+
+// Not returns the logical negation of v's truthiness.
+// Mirrors JavaScript's !v.
+func Not(v any) bool {
+	return !isTruthy(v)
+}
+
 // =============================================================================
 // String Operations
 // =============================================================================
@@ -215,17 +938,246 @@ func Join(items any, sep string) string {
 	return strings.Join(parts, sep)
 }
 
+// JoinField concatenates item.field of each element of a struct slice with
+// sep. field may be a dotted path like in Filter. Elements whose field is
+// missing contribute an empty string, as undefined does in JavaScript's join.
+// Mirrors JavaScript's items.map(item => item.field).join(sep).
+//
+// Usage in Go templates:
+//
+//	<p class="byline">{{bf_join_field .Authors "name" ", "}}</p>
+func JoinField(items any, field, sep string) string {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice {
+		return ""
+	}
+
+	parts := make([]string, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		if fieldVal, ok := resolveFieldPath(v.Index(i).Interface(), field); ok {
+			parts[i] = toString(fieldVal)
+		}
+	}
+	return strings.Join(parts, sep)
+}
+
+// Truncate cuts s to at most max runes and appends suffix when it was shortened.
+// Operates on runes so multibyte characters are never split.
+// Returns only the suffix if max <= 0.
+func Truncate(s string, max int, suffix string) string {
+	if max <= 0 {
+		return suffix
+	}
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + suffix
+}
+
+// Capitalize uppercases the first character of s, leaving the rest unchanged.
+// Mirrors s.charAt(0).toUpperCase() + s.slice(1).
+func Capitalize(s string) string {
+	return capitalize(s)
+}
+
+// Title capitalizes the first character of each whitespace-separated word.
+// Whitespace is preserved as-is.
+func Title(s string) string {
+	var buf strings.Builder
+	buf.Grow(len(s))
+	atWordStart := true
+	for _, r := range s {
+		if unicode.IsSpace(r) {
+			atWordStart = true
+			buf.WriteRune(r)
+			continue
+		}
+		if atWordStart {
+			r = unicode.ToUpper(r)
+			atWordStart = false
+		}
+		buf.WriteRune(r)
+	}
+	return buf.String()
+}
+
+// PadStart pads s on the left with pad until it is length runes long.
+// Mirrors JavaScript's String.prototype.padStart. Non-string input is
+// converted via toString so numbers can be padded directly.
+func PadStart(s any, length int, pad string) string {
+	str := toString(s)
+	return padding(str, length, pad) + str
+}
+
+// PadEnd pads s on the right with pad until it is length runes long.
+// Mirrors JavaScript's String.prototype.padEnd.
+func PadEnd(s any, length int, pad string) string {
+	str := toString(s)
+	return str + padding(str, length, pad)
+}
+
+// padding builds the fill needed to bring s up to length runes, repeating
+// pad and truncating the final chunk.
+func padding(s string, length int, pad string) string {
+	n := length - len([]rune(s))
+	padRunes := []rune(pad)
+	if n <= 0 || len(padRunes) == 0 {
+		return ""
+	}
+	fill := make([]rune, n)
+	for i := range fill {
+		fill[i] = padRunes[i%len(padRunes)]
+	}
+	return string(fill)
+}
+
+// =============================================================================
+// Number Formatting
+// =============================================================================
+
+// FormatNumber groups the integer part of v in threes separated by sep
+// (default ","), preserving any fractional part and keeping a leading minus
+// sign outside the grouping. e.g., FormatNumber(-1234567.5, ",") = "-1,234,567.5".
+// Mirrors n.toLocaleString('en-US') for the default separator.
+func FormatNumber(v any, sep string) string {
+	if sep == "" {
+		sep = ","
+	}
+	return groupThousands(strconv.FormatFloat(toFloat64(v), 'f', -1, 64), sep)
+}
+
+// Currency formats v as a currency amount: symbol prefix, comma-grouped
+// integer part, and exactly places decimals. Negative amounts put the sign
+// before the symbol, e.g. Currency(-1234.5, "$", 2) = "-$1,234.50".
+func Currency(v any, symbol string, places int) string {
+	num := groupThousands(toFixed(toFloat64(v), places), ",")
+	if strings.HasPrefix(num, "-") {
+		if strings.Trim(num, "-0.,") == "" {
+			// Rounded to zero: avoid "-$0.00"
+			return symbol + num[1:]
+		}
+		return "-" + symbol + num[1:]
+	}
+	return symbol + num
+}
+
+// Percent returns part/whole*100 formatted to places decimals with a
+// trailing "%", e.g. Percent(3, 5, 0) = "60%". Returns "0%" when whole is 0
+// (instead of "NaN%"), matching Div's zero guard.
+func Percent(part, whole any, places int) string {
+	w := toFloat64(whole)
+	if w == 0 {
+		return "0%"
+	}
+	return toFixed(toFloat64(part)/w*100, places) + "%"
+}
+
+// toFixed formats f with exactly places decimals, rounding half away from
+// zero like JavaScript's toFixed (FormatFloat alone rounds exact halves to
+// even). Negative places are treated as 0.
+func toFixed(f float64, places int) string {
+	if places < 0 {
+		places = 0
+	}
+	scale := math.Pow(10, float64(places))
+	return strconv.FormatFloat(math.Round(f*scale)/scale, 'f', places, 64)
+}
+
+// groupThousands inserts sep every three digits into the integer part of a
+// decimal number string.
+func groupThousands(num, sep string) string {
+	sign := ""
+	if strings.HasPrefix(num, "-") {
+		sign, num = "-", num[1:]
+	}
+	intPart, frac := num, ""
+	if i := strings.IndexByte(num, '.'); i >= 0 {
+		intPart, frac = num[:i], num[i:]
+	}
+
+	var b strings.Builder
+	for i, c := range intPart {
+		if i > 0 && (len(intPart)-i)%3 == 0 {
+			b.WriteString(sep)
+		}
+		b.WriteRune(c)
+	}
+	return sign + b.String() + frac
+}
+
+// =============================================================================
+// Date/Time Formatting
+// =============================================================================
+
+// Default layouts used by FormatDate and FormatTime when layout is empty.
+const (
+	DefaultDateLayout = "2006-01-02"
+	DefaultTimeLayout = "15:04"
+)
+
+// FormatDate formats t (a time.Time, *time.Time, or RFC3339 string) with
+// the Go layout, defaulting to DefaultDateLayout. Returns "" for invalid
+// input, nil, or the zero time.
+func FormatDate(t any, layout string) string {
+	if layout == "" {
+		layout = DefaultDateLayout
+	}
+	return formatTimeValue(t, layout)
+}
+
+// FormatTime formats t like FormatDate, defaulting to DefaultTimeLayout.
+func FormatTime(t any, layout string) string {
+	if layout == "" {
+		layout = DefaultTimeLayout
+	}
+	return formatTimeValue(t, layout)
+}
+
+func formatTimeValue(t any, layout string) string {
+	var tm time.Time
+	switch v := t.(type) {
+	case time.Time:
+		tm = v
+	case *time.Time:
+		if v == nil {
+			return ""
+		}
+		tm = *v
+	case string:
+		parsed, err := time.Parse(time.RFC3339, v)
+		if err != nil {
+			return ""
+		}
+		tm = parsed
+	default:
+		return ""
+	}
+	if tm.IsZero() {
+		return ""
+	}
+	return tm.Format(layout)
+}
+
 // =============================================================================
 // Array/Slice Operations
 // =============================================================================
 
 // Len returns the length of a slice, array, map, string, or channel.
-// Returns 0 for nil or unsupported types.
+// Pointers and interfaces are dereferenced first.
+// Returns 0 for nil (including nil pointers) or unsupported types.
 func Len(v any) int {
 	if v == nil {
 		return 0
 	}
 	rv := reflect.ValueOf(v)
+	// Unwrap pointers and interfaces (e.g., *[]T props fields)
+	for rv.Kind() == reflect.Ptr || rv.Kind() == reflect.Interface {
+		if rv.IsNil() {
+			return 0
+		}
+		rv = rv.Elem()
+	}
 	switch rv.Kind() {
 	case reflect.Slice, reflect.Array, reflect.Map, reflect.String, reflect.Chan:
 		return rv.Len()
@@ -237,7 +1189,14 @@ func Len(v any) int {
 // At returns the element at index i from a slice.
 // Supports negative indices (e.g., -1 for last element).
 // Returns nil if index is out of bounds.
+//
+// For a string, returns the character (rune) at index i as a one-rune
+// string, or "" if index is out of bounds (matching JS charAt).
 func At(items any, index int) any {
+	if s, ok := items.(string); ok {
+		return charAt(s, index)
+	}
+
 	v := reflect.ValueOf(items)
 	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
 		return nil
@@ -260,8 +1219,146 @@ func At(items any, index int) any {
 	return v.Index(index).Interface()
 }
 
+// charAt returns the rune at index i of s (negative counts from the end)
+// as a string, or "" if out of bounds.
+func charAt(s string, index int) string {
+	runes := []rune(s)
+	if index < 0 {
+		index = len(runes) + index
+	}
+	if index < 0 || index >= len(runes) {
+		return ""
+	}
+	return string(runes[index])
+}
+
+// Get returns the value stored under key in a map, or the element at index
+// key in a slice (same semantics as At). Numeric keys are coerced to the
+// map's numeric key type, so {{bf_get .ByID 3}} works for map[int64]T.
+// Returns nil when the key is absent or items is neither a map nor a slice.
+//
+// Usage in Go templates:
+//
+//	{{bf_get .SettingsMap "theme"}}
+func Get(items any, key any) any {
+	v := reflect.ValueOf(items)
+	switch v.Kind() {
+	case reflect.Slice, reflect.Array:
+		if !isIntLike(key) {
+			return nil
+		}
+		return At(items, toInt(key))
+	case reflect.Map:
+		k, ok := mapKey(v.Type().Key(), key)
+		if !ok {
+			return nil
+		}
+		val := v.MapIndex(k)
+		if !val.IsValid() {
+			return nil
+		}
+		return val.Interface()
+	default:
+		return nil
+	}
+}
+
+// mapKey converts key to a value of the map key type t. Numeric keys are
+// converted between numeric kinds only when the value is represented
+// exactly (3.7 never matches key 3, and -1 never matches a uint key);
+// other keys must be assignable or convertible within the same kind (a
+// number never becomes a string key).
+func mapKey(t reflect.Type, key any) (reflect.Value, bool) {
+	kv := reflect.ValueOf(key)
+	if !kv.IsValid() {
+		return reflect.Value{}, false
+	}
+	if kv.Type().AssignableTo(t) {
+		return kv, true
+	}
+	switch t.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64,
+		reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64,
+		reflect.Float32, reflect.Float64:
+		return numericMapKey(t, kv)
+	}
+	if kv.Kind() == t.Kind() && kv.Type().ConvertibleTo(t) {
+		return kv.Convert(t), true
+	}
+	return reflect.Value{}, false
+}
+
+// numericMapKey converts the numeric value kv to the numeric type t,
+// reporting false if kv is not numeric or t cannot represent it exactly.
+// Integer keys are converted directly rather than through float64 so large
+// int64/uint64 values keep their precision.
+func numericMapKey(t reflect.Type, kv reflect.Value) (reflect.Value, bool) {
+	k := reflect.New(t).Elem()
+	switch kv.Kind() {
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		i := kv.Int()
+		switch {
+		case k.CanInt():
+			if k.OverflowInt(i) {
+				return reflect.Value{}, false
+			}
+			k.SetInt(i)
+		case k.CanUint():
+			if i < 0 || k.OverflowUint(uint64(i)) {
+				return reflect.Value{}, false
+			}
+			k.SetUint(uint64(i))
+		default:
+			k.SetFloat(float64(i))
+		}
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		u := kv.Uint()
+		switch {
+		case k.CanInt():
+			if u > math.MaxInt64 || k.OverflowInt(int64(u)) {
+				return reflect.Value{}, false
+			}
+			k.SetInt(int64(u))
+		case k.CanUint():
+			if k.OverflowUint(u) {
+				return reflect.Value{}, false
+			}
+			k.SetUint(u)
+		default:
+			k.SetFloat(float64(u))
+		}
+	case reflect.Float32, reflect.Float64:
+		f := kv.Float()
+		if k.CanFloat() {
+			k.SetFloat(f)
+			return k, true
+		}
+		// Only integral values in range can match an integer key; the
+		// bounds are exclusive at the top because 2^63 and 2^64 are
+		// exactly representable as float64 but overflow.
+		if f != math.Trunc(f) {
+			return reflect.Value{}, false
+		}
+		if k.CanInt() {
+			if f < math.MinInt64 || f >= math.MaxInt64 || k.OverflowInt(int64(f)) {
+				return reflect.Value{}, false
+			}
+			k.SetInt(int64(f))
+		} else {
+			if f < 0 || f >= math.MaxUint64 || k.OverflowUint(uint64(f)) {
+				return reflect.Value{}, false
+			}
+			k.SetUint(uint64(f))
+		}
+	default:
+		return reflect.Value{}, false
+	}
+	return k, true
+}
+
 // Includes returns true if items contains elem.
-// Uses reflect.DeepEqual for comparison.
+// Compares like Eq: numbers by value across types (so []int contains
+// int64(2)), otherwise reflect.DeepEqual.
 func Includes(items any, elem any) bool {
 	v := reflect.ValueOf(items)
 	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
@@ -269,13 +1366,84 @@ func Includes(items any, elem any) bool {
 	}
 
 	for i := 0; i < v.Len(); i++ {
-		if reflect.DeepEqual(v.Index(i).Interface(), elem) {
+		if Eq(v.Index(i).Interface(), elem) {
+			return true
+		}
+	}
+	return false
+}
+
+// IncludesAny returns true if items contains at least one element of
+// candidates, comparing like Includes. Mirrors JavaScript's
+// candidates.some(c => items.includes(c)), so an empty candidates slice
+// yields false. Returns false if candidates is not a slice or array.
+//
+// Usage in Go templates:
+//
+//	{{if bf_includes_any .Tags .SelectedTags}}...{{end}}
+func IncludesAny(items any, candidates any) bool {
+	c := reflect.ValueOf(candidates)
+	if c.Kind() != reflect.Slice && c.Kind() != reflect.Array {
+		return false
+	}
+	for i := 0; i < c.Len(); i++ {
+		if Includes(items, c.Index(i).Interface()) {
 			return true
 		}
 	}
 	return false
 }
 
+// IncludesAll returns true if items contains every element of candidates,
+// comparing like Includes. Mirrors JavaScript's
+// candidates.every(c => items.includes(c)), so an empty candidates slice
+// yields true. Returns false if candidates is not a slice or array.
+func IncludesAll(items any, candidates any) bool {
+	c := reflect.ValueOf(candidates)
+	if c.Kind() != reflect.Slice && c.Kind() != reflect.Array {
+		return false
+	}
+	for i := 0; i < c.Len(); i++ {
+		if !Includes(items, c.Index(i).Interface()) {
+			return false
+		}
+	}
+	return true
+}
+
+// IndexOf returns the index of the first element equal to elem, or -1.
+// Uses reflect.DeepEqual, so it works on primitive slices ([]string, []int).
+// Mirrors JavaScript's Array.prototype.indexOf(elem).
+func IndexOf(items any, elem any) int {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return -1
+	}
+
+	for i := 0; i < v.Len(); i++ {
+		if reflect.DeepEqual(v.Index(i).Interface(), elem) {
+			return i
+		}
+	}
+	return -1
+}
+
+// LastIndexOf returns the index of the last element equal to elem, or -1.
+// Mirrors JavaScript's Array.prototype.lastIndexOf(elem).
+func LastIndexOf(items any, elem any) int {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return -1
+	}
+
+	for i := v.Len() - 1; i >= 0; i-- {
+		if reflect.DeepEqual(v.Index(i).Interface(), elem) {
+			return i
+		}
+	}
+	return -1
+}
+
 // First returns the first element of a slice, or nil if empty.
 func First(items any) any {
 	return At(items, 0)
@@ -286,6 +1454,273 @@ func Last(items any) any {
 	return At(items, -1)
 }
 
+// Reverse returns v reversed. Strings are reversed by rune; slices and arrays
+// return a new []any (non-mutating, like toReversed). A nil slice returns
+// an empty []any. Unsupported kinds are returned unchanged.
+func Reverse(v any) any {
+	if s, ok := v.(string); ok {
+		runes := []rune(s)
+		for i, j := 0, len(runes)-1; i < j; i, j = i+1, j-1 {
+			runes[i], runes[j] = runes[j], runes[i]
+		}
+		return string(runes)
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return v
+	}
+
+	length := rv.Len()
+	result := make([]any, length)
+	for i := 0; i < length; i++ {
+		result[i] = rv.Index(length - 1 - i).Interface()
+	}
+	return result
+}
+
+// SliceRange returns the elements of v from start up to (not including) end.
+// Negative indices count from the end, and out-of-range bounds are clamped,
+// mirroring JavaScript's Array.prototype.slice / String.prototype.slice.
+// Strings are sliced by rune; slices and arrays return a new []any.
+// Unsupported kinds are returned unchanged.
+func SliceRange(v any, start, end int) any {
+	if s, ok := v.(string); ok {
+		runes := []rune(s)
+		from, to := sliceBounds(len(runes), start, end)
+		return string(runes[from:to])
+	}
+
+	rv := reflect.ValueOf(v)
+	if rv.Kind() != reflect.Slice && rv.Kind() != reflect.Array {
+		return v
+	}
+
+	from, to := sliceBounds(rv.Len(), start, end)
+	result := make([]any, to-from)
+	for i := from; i < to; i++ {
+		result[i-from] = rv.Index(i).Interface()
+	}
+	return result
+}
+
+// Take returns the first n elements of items as a new []any, or the last -n
+// elements when n is negative (like slice(n) in JavaScript). n larger than
+// the length returns every element.
+func Take(items any, n int) []any {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+	if n < 0 {
+		return SliceRange(items, n, v.Len()).([]any)
+	}
+	return SliceRange(items, 0, n).([]any)
+}
+
+// Drop returns items without the first n elements as a new []any, or
+// without the last -n elements when n is negative. n >= length returns an
+// empty slice.
+func Drop(items any, n int) []any {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+	if n < 0 {
+		return SliceRange(items, 0, n).([]any)
+	}
+	return SliceRange(items, n, v.Len()).([]any)
+}
+
+// sliceBounds resolves JS-style slice indices against length, returning
+// clamped bounds with from <= to.
+func sliceBounds(length, start, end int) (int, int) {
+	clamp := func(i int) int {
+		if i < 0 {
+			i += length
+		}
+		if i < 0 {
+			return 0
+		}
+		if i > length {
+			return length
+		}
+		return i
+	}
+	from, to := clamp(start), clamp(end)
+	if to < from {
+		to = from
+	}
+	return from, to
+}
+
+// Range returns the integers from start up to (not including) end, advancing
+// by step. A negative step produces a descending range; a step of 0, or a
+// step pointing away from end, returns an empty slice.
+//
+// Usage in Go templates:
+//
+//	{{range bf_range 1 (bf_add .TotalPages 1) 1}}<a href="?page={{.}}">{{.}}</a>{{end}}
+func Range(start, end, step int) []int {
+	result := []int{}
+	switch {
+	case step > 0:
+		for i := start; i < end; i += step {
+			result = append(result, i)
+		}
+	case step < 0:
+		for i := start; i > end; i += step {
+			result = append(result, i)
+		}
+	}
+	return result
+}
+
+// Chunk splits items into consecutive sub-slices of at most size elements,
+// e.g. for rendering a grid row by row. A size <= 0 returns a single chunk
+// with every item. Empty input returns an empty result.
+//
+// Usage in Go templates:
+//
+//	{{range bf_chunk .Items 3}}<div class="row">{{range .}}...{{end}}</div>{{end}}
+func Chunk(items any, size int) [][]any {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+
+	length := v.Len()
+	if length == 0 {
+		return [][]any{}
+	}
+	if size <= 0 {
+		size = length
+	}
+
+	result := make([][]any, 0, (length+size-1)/size)
+	for start := 0; start < length; start += size {
+		end := start + size
+		if end > length {
+			end = length
+		}
+		chunk := make([]any, end-start)
+		for i := start; i < end; i++ {
+			chunk[i-start] = v.Index(i).Interface()
+		}
+		result = append(result, chunk)
+	}
+	return result
+}
+
+// Flatten flattens a slice of slices by one level into a single []any.
+// Elements that are not slices pass through unchanged.
+// Mirrors JavaScript's Array.prototype.flat() (depth 1).
+func Flatten(items any) []any {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+
+	result := make([]any, 0, v.Len())
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i)
+		if elem.Kind() == reflect.Interface {
+			elem = elem.Elem()
+		}
+		if elem.Kind() != reflect.Slice && elem.Kind() != reflect.Array {
+			result = append(result, v.Index(i).Interface())
+			continue
+		}
+		for j := 0; j < elem.Len(); j++ {
+			result = append(result, elem.Index(j).Interface())
+		}
+	}
+	return result
+}
+
+// PageResult is one page of items returned by Paginate.
+type PageResult struct {
+	Items      []any // Items on the requested page
+	Page       int   // Current page (1-based, clamped)
+	TotalPages int   // Number of pages (at least 1)
+	Total      int   // Total number of items
+}
+
+// Paginate returns the items on the given 1-based page along with pagination
+// metadata. Out-of-range pages are clamped to the first/last page. A perPage
+// <= 0 puts every item on a single page. Empty input yields one empty page.
+//
+// Usage in Go templates:
+//
+//	{{$p := bf_paginate .Items .Page 20}}{{range $p.Items}}...{{end}} Page {{$p.Page}} of {{$p.TotalPages}}
+func Paginate(items any, page, perPage int) PageResult {
+	total := Len(items)
+	if v := reflect.ValueOf(items); v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		total = 0
+	}
+	if perPage <= 0 {
+		perPage = total
+	}
+
+	totalPages := 1
+	if total > 0 && perPage > 0 {
+		totalPages = (total + perPage - 1) / perPage
+	}
+	if page < 1 {
+		page = 1
+	}
+	if page > totalPages {
+		page = totalPages
+	}
+
+	pageItems := []any{}
+	if total > 0 {
+		start := (page - 1) * perPage
+		pageItems = SliceRange(items, start, start+perPage).([]any)
+	}
+
+	return PageResult{
+		Items:      pageItems,
+		Page:       page,
+		TotalPages: totalPages,
+		Total:      total,
+	}
+}
+
+// Sum returns the total of a numeric slice.
+// Returns int when every element is int-like, float64 otherwise.
+// Mirrors arr.reduce((a, b) => a + b, 0).
+func Sum(items any) any {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return 0
+	}
+
+	total := 0.0
+	allInt := true
+	for i := 0; i < v.Len(); i++ {
+		elem := v.Index(i).Interface()
+		if !isIntLike(elem) {
+			allInt = false
+		}
+		total += toFloat64(elem)
+	}
+	if allInt && total == float64(int(total)) {
+		return int(total)
+	}
+	return total
+}
+
+// Avg returns the arithmetic mean of a numeric slice.
+// Returns 0 for an empty slice (instead of NaN).
+func Avg(items any) float64 {
+	n := Len(items)
+	if n == 0 {
+		return 0
+	}
+	return toFloat64(Sum(items)) / float64(n)
+}
+
 // =============================================================================
 // Higher-order Array Methods
 // =============================================================================
@@ -348,40 +1783,100 @@ func Some(items any, field string) bool {
 			return true
 		}
 	}
-	return false
+	return false
+}
+
+// EveryEq returns true if every item's field equals value (compared like
+// Eq, so an int literal matches an int64 field). Items without the field
+// do not match. Returns true for an empty slice.
+// Mirrors JavaScript's Array.prototype.every(item => item.field === value).
+func EveryEq(items any, field string, value any) bool {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+
+	capitalizedField := capitalize(field)
+	for i := 0; i < v.Len(); i++ {
+		fieldVal := getFieldValue(v.Index(i).Interface(), capitalizedField)
+		if fieldVal == nil || !Eq(fieldVal, value) {
+			return false
+		}
+	}
+	return true
+}
+
+// SomeEq returns true if at least one item's field equals value (compared
+// like Eq).
+// Mirrors JavaScript's Array.prototype.some(item => item.field === value).
+func SomeEq(items any, field string, value any) bool {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return false
+	}
+
+	capitalizedField := capitalize(field)
+	for i := 0; i < v.Len(); i++ {
+		fieldVal := getFieldValue(v.Index(i).Interface(), capitalizedField)
+		if fieldVal != nil && Eq(fieldVal, value) {
+			return true
+		}
+	}
+	return false
+}
+
+// Filter returns items where item.field == value.
+// field may be a dotted path into nested structs (e.g., "author.name").
+// Mirrors JavaScript's Array.prototype.filter(item => item.field === value).
+// Returns []any to allow chaining with other bf_* functions.
+func Filter(items any, field string, value any) []any {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+
+	var result []any
+
+	for i := 0; i < v.Len(); i++ {
+		fieldVal, ok := resolveFieldPath(v.Index(i).Interface(), field)
+		if !ok {
+			continue
+		}
+
+		// Compare field value with target value
+		if reflect.DeepEqual(fieldVal, value) {
+			result = append(result, v.Index(i).Interface())
+		}
+	}
+	return result
 }
 
-// Filter returns items where item.field == value.
-// Mirrors JavaScript's Array.prototype.filter(item => item.field === value).
-// Returns []any to allow chaining with other bf_* functions.
-func Filter(items any, field string, value any) []any {
+// FilterRange returns items whose numeric field lies within [lo, hi],
+// inclusive. field may be a dotted path like in Filter. Items whose field is
+// missing or not a number are excluded, and nil is returned if lo or hi is
+// not a number.
+// Mirrors JavaScript's items.filter(item => item.field >= lo && item.field <= hi).
+//
+// Usage in Go templates:
+//
+//	{{range bf_filter_range .Tasks "priority" 2 5}}...{{end}}
+func FilterRange(items any, field string, lo, hi any) []any {
 	v := reflect.ValueOf(items)
 	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
 		return nil
 	}
+	if !isNumeric(lo) || !isNumeric(hi) {
+		return nil
+	}
+	low, high := toFloat64(lo), toFloat64(hi)
 
-	capitalizedField := capitalize(field)
 	var result []any
-
 	for i := 0; i < v.Len(); i++ {
-		item := v.Index(i)
-		if item.Kind() == reflect.Interface {
-			item = item.Elem()
-		}
-		if item.Kind() == reflect.Ptr {
-			item = item.Elem()
-		}
-		if item.Kind() != reflect.Struct {
-			continue
-		}
-
-		fieldVal := item.FieldByName(capitalizedField)
-		if !fieldVal.IsValid() {
+		fieldVal, ok := resolveFieldPath(v.Index(i).Interface(), field)
+		if !ok || !isNumeric(fieldVal) {
 			continue
 		}
-
-		// Compare field value with target value
-		if reflect.DeepEqual(fieldVal.Interface(), value) {
+		if n := toFloat64(fieldVal); n >= low && n <= high {
 			result = append(result, v.Index(i).Interface())
 		}
 	}
@@ -389,6 +1884,7 @@ func Filter(items any, field string, value any) []any {
 }
 
 // Find returns the first item where item.field == value, or nil if not found.
+// field may be a dotted path into nested structs (e.g., "author.name").
 // Mirrors JavaScript's Array.prototype.find(item => item.field === value).
 func Find(items any, field string, value any) any {
 	v := reflect.ValueOf(items)
@@ -396,25 +1892,13 @@ func Find(items any, field string, value any) any {
 		return nil
 	}
 
-	capitalizedField := capitalize(field)
 	for i := 0; i < v.Len(); i++ {
-		item := v.Index(i)
-		if item.Kind() == reflect.Interface {
-			item = item.Elem()
-		}
-		if item.Kind() == reflect.Ptr {
-			item = item.Elem()
-		}
-		if item.Kind() != reflect.Struct {
-			continue
-		}
-
-		fieldVal := item.FieldByName(capitalizedField)
-		if !fieldVal.IsValid() {
+		fieldVal, ok := resolveFieldPath(v.Index(i).Interface(), field)
+		if !ok {
 			continue
 		}
 
-		if reflect.DeepEqual(fieldVal.Interface(), value) {
+		if reflect.DeepEqual(fieldVal, value) {
 			return v.Index(i).Interface()
 		}
 	}
@@ -422,6 +1906,7 @@ func Find(items any, field string, value any) any {
 }
 
 // FindIndex returns the index of the first item where item.field == value, or -1.
+// field may be a dotted path into nested structs (e.g., "author.name").
 // Mirrors JavaScript's Array.prototype.findIndex(item => item.field === value).
 func FindIndex(items any, field string, value any) int {
 	v := reflect.ValueOf(items)
@@ -429,25 +1914,32 @@ func FindIndex(items any, field string, value any) int {
 		return -1
 	}
 
-	capitalizedField := capitalize(field)
 	for i := 0; i < v.Len(); i++ {
-		item := v.Index(i)
-		if item.Kind() == reflect.Interface {
-			item = item.Elem()
-		}
-		if item.Kind() == reflect.Ptr {
-			item = item.Elem()
-		}
-		if item.Kind() != reflect.Struct {
+		fieldVal, ok := resolveFieldPath(v.Index(i).Interface(), field)
+		if !ok {
 			continue
 		}
 
-		fieldVal := item.FieldByName(capitalizedField)
-		if !fieldVal.IsValid() {
-			continue
+		if reflect.DeepEqual(fieldVal, value) {
+			return i
 		}
+	}
+	return -1
+}
+
+// FirstIncomplete returns the index of the first item whose bool field is
+// false (or missing), or -1 if every item is complete.
+// Mirrors JavaScript's Array.prototype.findIndex(item => !item.field).
+func FirstIncomplete(items any, doneField string) int {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return -1
+	}
 
-		if reflect.DeepEqual(fieldVal.Interface(), value) {
+	capitalizedField := capitalize(doneField)
+	for i := 0; i < v.Len(); i++ {
+		done, _ := getFieldValue(v.Index(i).Interface(), capitalizedField).(bool)
+		if !done {
 			return i
 		}
 	}
@@ -490,6 +1982,68 @@ func Sort(items any, field string, direction string) []any {
 	return result
 }
 
+// SortValues returns a new slice of the primitive elements of items sorted
+// in the given direction ("asc" or "desc"). Strings compare
+// lexicographically; other values compare numerically via toFloat64. Like
+// Sort it is stable and non-mutating, returns []any{} for an empty slice and
+// nil for a non-slice.
+// Mirrors JavaScript's Array.prototype.toSorted() on a []number or []string.
+//
+// Usage in Go templates:
+//
+//	{{range bf_sort_values .Tags "asc"}}...{{end}}
+func SortValues(items any, direction string) []any {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return nil
+	}
+
+	result := make([]any, v.Len())
+	for i := range result {
+		result[i] = v.Index(i).Interface()
+	}
+
+	sort.SliceStable(result, func(i, j int) bool {
+		a, b := result[i], result[j]
+		if direction == "desc" {
+			a, b = b, a
+		}
+		sa, aIsString := a.(string)
+		sb, bIsString := b.(string)
+		if aIsString && bIsString {
+			return sa < sb
+		}
+		return toFloat64(a) < toFloat64(b)
+	})
+
+	return result
+}
+
+// GroupSums groups items by groupField and returns each group's total of sumField.
+// Group keys are the string form of the group field value. Non-numeric sum
+// values contribute 0, matching how Sort coerces field values.
+// Mirrors items.reduce((acc, item) => { acc[item.group] += item.amount }, {}).
+func GroupSums(items any, groupField, sumField string) map[string]float64 {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return map[string]float64{}
+	}
+
+	capitalizedGroup := capitalize(groupField)
+	capitalizedSum := capitalize(sumField)
+	result := make(map[string]float64)
+
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i).Interface()
+		key := getFieldValue(item, capitalizedGroup)
+		if key == nil {
+			continue
+		}
+		result[toString(key)] += toFloat64(getFieldValue(item, capitalizedSum))
+	}
+	return result
+}
+
 // getFieldValue extracts a struct field value using reflection.
 func getFieldValue(item any, field string) any {
 	v := reflect.ValueOf(item)
@@ -510,12 +2064,38 @@ func getFieldValue(item any, field string) any {
 	return fieldVal.Interface()
 }
 
+// resolveFieldPath walks a dotted field path (e.g., "author.name") through
+// nested structs, dereferencing pointers and interfaces along the way. Each
+// segment is capitalized like getFieldValue's callers do. Returns false when
+// a segment is missing or an intermediate value is nil or not a struct.
+func resolveFieldPath(item any, path string) (any, bool) {
+	v := reflect.ValueOf(item)
+	for _, name := range strings.Split(path, ".") {
+		for v.Kind() == reflect.Interface || v.Kind() == reflect.Ptr {
+			if v.IsNil() {
+				return nil, false
+			}
+			v = v.Elem()
+		}
+		if v.Kind() != reflect.Struct {
+			return nil, false
+		}
+		v = v.FieldByName(capitalize(name))
+		if !v.IsValid() {
+			return nil, false
+		}
+	}
+	return v.Interface(), true
+}
+
 // capitalize uppercases the first character of a string.
+// Rune-safe so a leading multibyte character is not split.
 func capitalize(s string) string {
-	if s == "" {
+	r, size := utf8.DecodeRuneInString(s)
+	if size == 0 || r == utf8.RuneError {
 		return s
 	}
-	return strings.ToUpper(s[:1]) + s[1:]
+	return string(unicode.ToUpper(r)) + s[size:]
 }
 
 // =============================================================================
@@ -524,20 +2104,85 @@ func capitalize(s string) string {
 
 // Comment returns an HTML comment string for hydration markers.
 // The "bf-" prefix is automatically added.
+//
+// Range markers come in open/close pairs sharing the same content: Comment
+// opens the range and CommentEnd closes it, so the client runtime can find
+// the nodes between them:
+//
+//	<!--bf-cond:slot_0-->...<!--/bf-cond:slot_0-->
 func Comment(content string) template.HTML {
 	return template.HTML("<!--bf-" + content + "-->")
 }
 
+// CommentEnd returns the close marker paired with Comment(content).
+// Format: <!--/bf-content-->
+func CommentEnd(content string) template.HTML {
+	return template.HTML("<!--/bf-" + content + "-->")
+}
+
 // TextStart returns an HTML comment start marker for reactive text expressions.
 // Format: <!--bf:slotId-->
+//
+// TextStart and TextEnd bracket the text of a reactive expression:
+//
+//	<!--bf:s0-->Hello<!--/-->
+//
+// The client runtime ($t) walks comment nodes for "bf:slotId" and binds the
+// text node immediately after the start marker (creating an empty one when
+// the rendered value is empty); the end marker keeps that text node separate
+// from any adjacent static text.
 func TextStart(slotId string) template.HTML {
 	return template.HTML("<!--bf:" + slotId + "-->")
 }
 
 // TextEnd returns an HTML comment end marker for reactive text expressions.
-// Format: <!--/-->
-func TextEnd() template.HTML {
-	return "<!--/-->"
+// Without an id it emits the anonymous marker compiled templates use
+// ({{bfTextEnd}}); with an id it emits a close marker naming the slot.
+// Format: <!--/--> or <!--/bf:slotId-->
+func TextEnd(slotId ...string) template.HTML {
+	if len(slotId) == 0 || slotId[0] == "" {
+		return "<!--/-->"
+	}
+	return template.HTML("<!--/bf:" + slotId[0] + "-->")
+}
+
+// ListStart returns the start marker of a keyed list region.
+// Format: <!--bf-list:id-->
+//
+// ListStart and ListEnd bracket the items rendered by a range, so the client
+// runtime can locate the list region and insert, move or remove keyed items
+// (see Key) between the markers:
+//
+//	<!--bf-list:s3--><li bf-key="1">a</li><li bf-key="2">b</li><!--/bf-list:s3-->
+func ListStart(id string) template.HTML {
+	return template.HTML("<!--bf-list:" + id + "-->")
+}
+
+// ListEnd returns the end marker paired with ListStart(id).
+// Format: <!--/bf-list:id-->
+func ListEnd(id string) template.HTML {
+	return template.HTML("<!--/bf-list:" + id + "-->")
+}
+
+// Key returns a bf-key attribute holding the stringified key (via
+// fmt.Sprint, like ScopeID, so every numeric kind including uint IDs works)
+// of a list item, so the client runtime can match items across re-renders
+// when a list is reordered. The compiler should place it on the root
+// element of each item rendered by a range:
+//
+//	{{range .Todos}}<li {{bfKey .ID}}>...</li>{{end}}
+//
+// Returns an empty attribute for a nil key or one that stringifies to "".
+// Format: bf-key="42"
+func Key(v any) template.HTMLAttr {
+	if v == nil {
+		return ""
+	}
+	key := fmt.Sprint(v)
+	if key == "" {
+		return ""
+	}
+	return template.HTMLAttr(`bf-key="` + template.HTMLEscapeString(key) + `"`)
 }
 
 // ScopeComment outputs a comment-based scope marker for fragment root components.
@@ -548,7 +2193,7 @@ func ScopeComment(props interface{}) template.HTML {
 	propsJSON := ""
 	if getBoolField(props, "BfIsRoot") {
 		// Build flat props JSON (same as BfPropsAttr but without the attribute wrapper)
-		pJSON, err := json.Marshal(props)
+		pJSON, err := MarshalPropsStable(props)
 		if err == nil {
 			propsJSON = "|" + string(pJSON)
 		}
@@ -556,15 +2201,276 @@ func ScopeComment(props interface{}) template.HTML {
 	return template.HTML("<!--bf-scope:" + scopeAttr + propsJSON + "-->")
 }
 
+// FocusTarget returns a data-bf-focus attribute naming the element the client
+// should focus/scroll to after hydration. Returns an empty attribute when
+// selector is empty.
+// Format: data-bf-focus="selector"
+func FocusTarget(selector string) template.HTMLAttr {
+	if selector == "" {
+		return ""
+	}
+	return template.HTMLAttr(`data-bf-focus="` + template.HTMLEscapeString(selector) + `"`)
+}
+
+// DataJSON returns a data-<name> attribute holding v as JSON, for embedding
+// element-level data (e.g., chart series) the client reads on hydration.
+// The value is wrapped in single quotes; single quotes inside the JSON are
+// escaped as &#39;. Returns an empty attribute if name is not made of
+// [A-Za-z0-9_-] or if v cannot be marshaled.
+// Format: data-name='{"key":"value"}'
+func DataJSON(name string, v any) template.HTMLAttr {
+	if !attrNamePattern.MatchString(name) {
+		return ""
+	}
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	// json.Marshal already escapes <, > and & as \u003c etc.
+	escaped := strings.ReplaceAll(string(data), "'", "&#39;")
+	return template.HTMLAttr("data-" + name + "='" + escaped + "'")
+}
+
+// attrNamePattern matches the attribute names helpers that return trusted
+// HTMLAttr/HTML markup accept (DataJSON's after "data-"). It excludes
+// whitespace, quotes, '=' and '>' so a name can't inject extra attributes.
+var attrNamePattern = regexp.MustCompile(`^[A-Za-z0-9_-]+$`)
+
+// JSONString marshals v to JSON for use as a quoted attribute value in a
+// template. json.Marshal escapes <, > and & as \u003c, \u003e and \u0026,
+// and html/template escapes the quotes for the attribute context, so the
+// value is left otherwise unescaped to avoid double escaping.
+// Returns "" if v cannot be marshaled.
+//
+// Usage in Go templates:
+//
+//	<div data-config="{{bf_json .Config}}">
+func JSONString(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return string(data)
+}
+
+// JSONAttr marshals v to JSON and HTML-escapes it the same way BfPropsAttr
+// escapes bf-p, for code that assembles attribute markup outside the
+// template's contextual escaping (e.g., a layout func building the page).
+// The result is an attribute value to place between double quotes, not a
+// name="value" pair, so it is returned as a plain string.
+// Returns "" if v cannot be marshaled.
+func JSONAttr(v any) string {
+	data, err := json.Marshal(v)
+	if err != nil {
+		return ""
+	}
+	return template.HTMLEscapeString(string(data))
+}
+
+// SafeHTML marks s as trusted HTML so the template outputs it unescaped,
+// e.g., markdown that was rendered and sanitized on the server. Unlike
+// PortalHTML it does not parse s as a template; it only wraps the string.
+//
+// WARNING: this disables html/template's XSS protection for s. Never pass
+// user-supplied or unsanitized content.
+//
+// Usage in Go templates:
+//
+//	<article>{{bf_safe_html .BodyHTML}}</article>
+func SafeHTML(s string) template.HTML {
+	return template.HTML(s)
+}
+
+// NL2BR HTML-escapes s and then turns each line break into <br>, so
+// multiline user text keeps its lines without opening an XSS hole.
+// "\r\n" and a lone "\r" count as a single break.
+//
+// Usage in Go templates:
+//
+//	<p>{{bf_nl2br .Description}}</p>
+func NL2BR(s string) template.HTML {
+	escaped := template.HTMLEscapeString(s)
+	escaped = strings.ReplaceAll(escaped, "\r\n", "\n")
+	escaped = strings.ReplaceAll(escaped, "\r", "\n")
+	return template.HTML(strings.ReplaceAll(escaped, "\n", "<br>"))
+}
+
+// Class composes a class string from its arguments, mirroring the clsx
+// helper used by components on the client so SSR output matches:
+//   - a string is included (empty strings are skipped)
+//   - a string followed by a bool is included only when the bool is true
+//   - a map[string]bool includes each key whose value is true, in sorted
+//     key order for deterministic output
+//   - a []string includes each non-empty element
+//
+// Results are joined with single spaces and trimmed.
+//
+// Usage in Go templates:
+//
+//	<button class="{{bf_class "btn" "btn-active" .Active .Variants}}">
+func Class(args ...any) string {
+	var classes []string
+	add := func(c string) {
+		classes = append(classes, strings.Fields(c)...)
+	}
+
+	for i := 0; i < len(args); i++ {
+		switch v := args[i].(type) {
+		case string:
+			if i+1 < len(args) {
+				if cond, ok := args[i+1].(bool); ok {
+					i++
+					if !cond {
+						continue
+					}
+				}
+			}
+			add(v)
+		case map[string]bool:
+			keys := make([]string, 0, len(v))
+			for k, on := range v {
+				if on {
+					keys = append(keys, k)
+				}
+			}
+			sort.Strings(keys)
+			for _, k := range keys {
+				add(k)
+			}
+		case []string:
+			for _, c := range v {
+				add(c)
+			}
+		}
+	}
+	return strings.Join(classes, " ")
+}
+
+// Attr returns a boolean attribute in its name="name" form when cond is true,
+// and an empty attribute otherwise. The attribute is also empty if name is
+// not made of [A-Za-z0-9_-].
+//
+// Usage in Go templates:
+//
+//	<button {{bf_attr "disabled" .Disabled}}>
+func Attr(name string, cond bool) template.HTMLAttr {
+	if !cond || !attrNamePattern.MatchString(name) {
+		return ""
+	}
+	return template.HTMLAttr(name + `="` + name + `"`)
+}
+
+// AttrVal returns name="value" when cond is true, and an empty attribute
+// otherwise. The value is converted with toString and HTML-escaped; the
+// attribute is also empty if name is not made of [A-Za-z0-9_-].
+//
+// Usage in Go templates:
+//
+//	<input {{bf_attr_val "aria-describedby" .ErrorID .HasError}}>
+func AttrVal(name string, value any, cond bool) template.HTMLAttr {
+	if !cond || !attrNamePattern.MatchString(name) {
+		return ""
+	}
+	return template.HTMLAttr(name + `="` + template.HTMLEscapeString(toString(value)) + `"`)
+}
+
+// Style renders a map of CSS properties (e.g., map[string]string or
+// map[string]any) as a style attribute with keys sorted for deterministic,
+// hydration-safe output. Values are formatted with fmt.Sprint; nil and empty
+// values are skipped, as are keys that are not CSS property names (letters,
+// digits, '-' and '_', optionally with a leading "-" or "--"). Returns an
+// empty attribute for an empty or non-map argument.
+//
+// The result is a trusted HTMLAttr, so it bypasses html/template's CSS
+// sanitizer: values are only HTML-escaped, and a value containing ';' or
+// url(...) is emitted as written. Keys and values must not be
+// user-controlled.
+// Format: style="k1:v1;k2:v2"
+func Style(m any) template.HTMLAttr {
+	v := reflect.ValueOf(m)
+	if v.Kind() != reflect.Map || v.Type().Key().Kind() != reflect.String {
+		return ""
+	}
+
+	keys := v.MapKeys()
+	sort.Slice(keys, func(i, j int) bool { return keys[i].String() < keys[j].String() })
+
+	decls := make([]string, 0, len(keys))
+	for _, k := range keys {
+		if !cssPropertyPattern.MatchString(k.String()) {
+			continue
+		}
+		raw := v.MapIndex(k).Interface()
+		if raw == nil {
+			continue
+		}
+		val := fmt.Sprint(raw)
+		if val == "" {
+			continue
+		}
+		decls = append(decls, k.String()+":"+val)
+	}
+	if len(decls) == 0 {
+		return ""
+	}
+	return template.HTMLAttr(`style="` + template.HTMLEscapeString(strings.Join(decls, ";")) + `"`)
+}
+
+// cssPropertyPattern matches the property names Style accepts, including
+// vendor-prefixed (-webkit-...) and custom (--accent) properties.
+var cssPropertyPattern = regexp.MustCompile(`^-{0,2}[A-Za-z][A-Za-z0-9_-]*$`)
+
+// MotionExtraKey is the RenderOptions.Extra key holding the reduced-motion
+// preference, populated from the Sec-CH-Prefers-Reduced-Motion client hint.
+const MotionExtraKey = "PrefersReducedMotion"
+
+// MotionHint normalizes the Sec-CH-Prefers-Reduced-Motion request header to
+// "reduce" or "no-preference". Store the result in Extra[MotionExtraKey]:
+//
+//	extra := map[string]interface{}{bf.MotionExtraKey: bf.MotionHint(c.Request())}
+func MotionHint(r *http.Request) string {
+	if r != nil && strings.TrimSpace(r.Header.Get("Sec-CH-Prefers-Reduced-Motion")) == "reduce" {
+		return "reduce"
+	}
+	return "no-preference"
+}
+
+// Motion returns the reduced-motion preference stored in extra under
+// MotionExtraKey: "reduce" or "no-preference" (the default when unset).
+//
+// Usage in Go templates:
+//
+//	{{if eq (bf_motion .Extra) "reduce"}}<img src="static.png">{{else}}<video autoplay></video>{{end}}
+func Motion(extra map[string]interface{}) string {
+	if v, ok := extra[MotionExtraKey].(string); ok && v == "reduce" {
+		return "reduce"
+	}
+	return "no-preference"
+}
+
 // PortalHTML parses and executes a template string with the provided data.
 // Used for rendering dynamic portal content where the template string
 // contains Go template expressions (e.g., {{if .Open}}open{{end}}).
 //
-// The template string is parsed fresh each time to support dynamic content.
+// Parsed templates are cached by template string, so a portal rendered
+// inside a range parses once; each call still executes with its own data.
 // Standard Go template functions (if, range, eq, etc.) are available.
 func PortalHTML(data interface{}, tmplStr string) template.HTML {
-	// Create a new template with the FuncMap for custom functions
-	t, err := template.New("portal").Funcs(FuncMap()).Parse(tmplStr)
+	return PortalHTMLFuncs(data, tmplStr, nil)
+}
+
+// PortalHTMLFuncs is like PortalHTML but merges extra over the base FuncMap,
+// so portal template strings can use app-specific helpers. Templates parsed
+// with a non-empty extra are not cached (function values cannot key the
+// cache), so prefer PortalHTML on hot paths that don't need extra helpers.
+func PortalHTMLFuncs(data interface{}, tmplStr string, extra template.FuncMap) template.HTML {
+	var t *template.Template
+	var err error
+	if len(extra) == 0 {
+		t, err = portalTemplate(tmplStr)
+	} else {
+		t, err = template.New("portal").Funcs(FuncMap()).Funcs(extra).Parse(tmplStr)
+	}
 	if err != nil {
 		// Return error message as HTML comment for debugging
 		return template.HTML("<!-- bfPortalHTML error: " + err.Error() + " -->")
@@ -578,6 +2484,24 @@ func PortalHTML(data interface{}, tmplStr string) template.HTML {
 	return template.HTML(buf.String())
 }
 
+// portalTemplates caches parsed PortalHTML templates keyed by template string.
+var portalTemplates sync.Map // map[string]*template.Template
+
+// portalTemplate returns the parsed template for tmplStr, parsing it on first
+// use. Parse errors are not cached.
+func portalTemplate(tmplStr string) (*template.Template, error) {
+	if t, ok := portalTemplates.Load(tmplStr); ok {
+		return t.(*template.Template), nil
+	}
+	// Create a new template with the FuncMap for custom functions
+	t, err := template.New("portal").Funcs(FuncMap()).Parse(tmplStr)
+	if err != nil {
+		return nil, err
+	}
+	actual, _ := portalTemplates.LoadOrStore(tmplStr, t)
+	return actual.(*template.Template), nil
+}
+
 // =============================================================================
 // Portal Collection
 // =============================================================================
@@ -586,14 +2510,19 @@ func PortalHTML(data interface{}, tmplStr string) template.HTML {
 type PortalContent struct {
 	ID      string        // Unique portal ID for hydration matching
 	OwnerID string        // Owner scope ID for find() support
+	Target  string        // Target container selector (empty means body end)
 	Content template.HTML // Portal HTML content
 }
 
 // PortalCollector collects portal content during template rendering.
 // Portal content is rendered at </body> to avoid z-index issues.
+// A PortalCollector is safe for concurrent use by multiple goroutines,
+// so one collector can be shared by components rendered in parallel.
 type PortalCollector struct {
+	mu      sync.Mutex
 	portals []PortalContent
 	counter int
+	keys    map[string]bool // keys registered via AddKeyed
 }
 
 // NewPortalCollector creates a new PortalCollector.
@@ -606,71 +2535,189 @@ func NewPortalCollector() *PortalCollector {
 
 // Add registers portal content to be rendered at body end.
 func (pc *PortalCollector) Add(ownerID string, content template.HTML) string {
+	return pc.AddTo(ownerID, "", content)
+}
+
+// AddTo registers portal content to be mounted into the container matching
+// the target selector (e.g., "#modal-root"). The wrapper div carries a
+// bf-pt attribute so the client runtime can relocate it; an empty target
+// behaves like Add.
+func (pc *PortalCollector) AddTo(ownerID, target string, content template.HTML) string {
+	pc.mu.Lock()
+	defer pc.mu.Unlock()
 	pc.counter++
 	id := "bf-portal-" + strconv.Itoa(pc.counter)
 	pc.portals = append(pc.portals, PortalContent{
 		ID:      id,
 		OwnerID: ownerID,
+		Target:  target,
 		Content: content,
 	})
 	return "" // Return empty string for template use
 }
 
-// Render outputs all collected portals as HTML.
-// Each portal is wrapped in a div with bf-pi (portal ID) and bf-po (portal owner).
-func (pc *PortalCollector) Render() template.HTML {
-	if pc == nil || len(pc.portals) == 0 {
+// AddKeyed registers portal content like Add, but only once per key: if key
+// was already added, the later content is ignored. Use it when several
+// component instances share one portal (e.g., a tooltip rendered per item)
+// so the portal is emitted once instead of once per instance.
+func (pc *PortalCollector) AddKeyed(ownerID, key string, content template.HTML) string {
+	pc.mu.Lock()
+	if pc.keys[key] {
+		pc.mu.Unlock()
 		return ""
 	}
-	var buf strings.Builder
-	for _, p := range pc.portals {
-		buf.WriteString(`<div bf-pi="`)
-		buf.WriteString(p.ID)
-		buf.WriteString(`" bf-po="`)
-		buf.WriteString(p.OwnerID)
-		buf.WriteString(`">`)
-		buf.WriteString(string(p.Content))
-		buf.WriteString("</div>\n")
+	if pc.keys == nil {
+		pc.keys = map[string]bool{}
 	}
+	pc.keys[key] = true
+	pc.mu.Unlock()
+	return pc.Add(ownerID, content)
+}
+
+// Render outputs all collected portals as HTML.
+// Each portal is wrapped in a div with bf-pi (portal ID) and bf-po (portal owner),
+// plus bf-pt (target selector) for portals added with a target.
+func (pc *PortalCollector) Render() template.HTML {
+	var buf strings.Builder
+	pc.RenderStream(&buf) // strings.Builder writes never fail
 	return template.HTML(buf.String())
 }
 
+// RenderStream writes the collected portals to w one wrapper div at a time,
+// in the same format as Render, instead of building the whole string in
+// memory first. A layout writing a large page directly to the response can
+// call it at body end. Returns the first write error.
+func (pc *PortalCollector) RenderStream(w io.Writer) error {
+	if pc == nil {
+		return nil
+	}
+	// Snapshot under the lock so a concurrent Add cannot affect this render.
+	pc.mu.Lock()
+	portals := make([]PortalContent, len(pc.portals))
+	copy(portals, pc.portals)
+	pc.mu.Unlock()
+
+	for _, p := range portals {
+		parts := []string{`<div bf-pi="`, p.ID, `" bf-po="`, p.OwnerID}
+		if p.Target != "" {
+			parts = append(parts, `" bf-pt="`, template.HTMLEscapeString(p.Target))
+		}
+		parts = append(parts, `">`, string(p.Content), "</div>\n")
+		for _, part := range parts {
+			if _, err := io.WriteString(w, part); err != nil {
+				return err
+			}
+		}
+	}
+	return nil
+}
+
 // =============================================================================
 // Script Collection
 // =============================================================================
 
+// ScriptOptions configures optional attributes on a registered <script> tag.
+type ScriptOptions struct {
+	// Async adds the async attribute.
+	Async bool
+
+	// Defer adds the defer attribute.
+	Defer bool
+
+	// Nonce sets the nonce attribute (for Content-Security-Policy).
+	Nonce string
+}
+
 // ScriptCollector collects client scripts with deduplication.
 // It preserves insertion order for deterministic output.
+// A ScriptCollector is safe for concurrent use by multiple goroutines.
 type ScriptCollector struct {
-	scripts map[string]bool
+	mu      sync.RWMutex
+	scripts map[string]ScriptOptions
 	order   []string
+
+	inline      map[string]bool // content hashes of registered inline scripts
+	inlineOrder []string
 }
 
 // NewScriptCollector creates a new ScriptCollector.
 func NewScriptCollector() *ScriptCollector {
 	return &ScriptCollector{
-		scripts: make(map[string]bool),
-		order:   []string{},
+		scripts:     make(map[string]ScriptOptions),
+		order:       []string{},
+		inline:      make(map[string]bool),
+		inlineOrder: []string{},
 	}
 }
 
 // Register adds a script source to the collection.
 // Duplicate scripts are ignored (only first registration counts).
 func (sc *ScriptCollector) Register(src string) string {
-	if sc.scripts[src] {
+	return sc.RegisterWith(src, ScriptOptions{})
+}
+
+// RegisterWith adds a script source with extra tag attributes.
+// Deduplication keys on src only: if src is already registered, the call is
+// ignored and the first registration's options are kept.
+func (sc *ScriptCollector) RegisterWith(src string, opts ScriptOptions) string {
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if _, ok := sc.scripts[src]; ok {
 		return "" // Already registered
 	}
-	sc.scripts[src] = true
+	sc.scripts[src] = opts
 	sc.order = append(sc.order, src)
 	return "" // Return empty string for template use
 }
 
 // Scripts returns all registered scripts in insertion order.
+// The returned slice is a copy and is safe to use while Register is called.
 func (sc *ScriptCollector) Scripts() []string {
-	return sc.order
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	order := make([]string, len(sc.order))
+	copy(order, sc.order)
+	return order
+}
+
+// RegisterInline adds an inline module script body to the collection.
+// Identical bodies are deduplicated by content hash; insertion order is kept.
+//
+// The code is emitted verbatim by BfScripts without any escaping. Only pass
+// trusted, developer-authored JavaScript — never user input.
+func (sc *ScriptCollector) RegisterInline(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	key := hex.EncodeToString(sum[:])
+
+	sc.mu.Lock()
+	defer sc.mu.Unlock()
+	if sc.inline[key] {
+		return "" // Already registered
+	}
+	sc.inline[key] = true
+	sc.inlineOrder = append(sc.inlineOrder, code)
+	return "" // Return empty string for template use
+}
+
+// InlineScripts returns all registered inline script bodies in insertion order.
+func (sc *ScriptCollector) InlineScripts() []string {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	codes := make([]string, len(sc.inlineOrder))
+	copy(codes, sc.inlineOrder)
+	return codes
+}
+
+// Options returns the options src was registered with.
+func (sc *ScriptCollector) Options(src string) ScriptOptions {
+	sc.mu.RLock()
+	defer sc.mu.RUnlock()
+	return sc.scripts[src]
 }
 
 // BfScripts generates script tags for all registered scripts.
+// External scripts are emitted first, followed by inline scripts, each in
+// insertion order. Inline script bodies are trusted and not escaped.
 // Returns HTML safe for embedding in templates.
 func BfScripts(collector *ScriptCollector) template.HTML {
 	if collector == nil {
@@ -678,9 +2725,28 @@ func BfScripts(collector *ScriptCollector) template.HTML {
 	}
 	var result strings.Builder
 	for _, src := range collector.Scripts() {
+		opts := collector.Options(src)
 		result.WriteString(`<script type="module" src="`)
 		result.WriteString(src)
-		result.WriteString(`"></script>`)
+		result.WriteString(`"`)
+		if opts.Async {
+			result.WriteString(` async`)
+		}
+		if opts.Defer {
+			result.WriteString(` defer`)
+		}
+		if opts.Nonce != "" {
+			result.WriteString(` nonce="`)
+			result.WriteString(template.HTMLEscapeString(opts.Nonce))
+			result.WriteString(`"`)
+		}
+		result.WriteString(`></script>`)
+		result.WriteString("\n")
+	}
+	for _, code := range collector.InlineScripts() {
+		result.WriteString(`<script type="module">`)
+		result.WriteString(code)
+		result.WriteString(`</script>`)
 		result.WriteString("\n")
 	}
 	return template.HTML(result.String())
@@ -716,15 +2782,37 @@ type RenderContext struct {
 
 	// Extra holds additional user-defined data for the layout
 	Extra map[string]interface{}
+
+	// Head contains the deduplicated RenderOptions.Head entries, one per
+	// line, for the layout to emit inside <head>
+	Head template.HTML
+
+	// Ctx is the context passed to RenderCtx (context.Background() for the
+	// other Render methods), so layouts can read request-scoped values such
+	// as locale or user.
+	Ctx context.Context
 }
 
 // LayoutFunc renders the final HTML page given the render context.
 type LayoutFunc func(ctx *RenderContext) string
 
 // Renderer renders BarefootJS components with a customizable layout.
+//
+// A Renderer holds the parsed template set it was created with and is safe
+// to share across requests; build it once at startup (see
+// MustLoadTemplates) rather than re-parsing templates per request.
 type Renderer struct {
 	templates *template.Template
 	layout    LayoutFunc
+
+	// pristine is an unexecuted clone of templates used as the base for
+	// RenderOptions.Funcs overrides (html/template cannot Clone a set that
+	// has already been executed). It is taken by snapshot just before the
+	// Renderer first executes the set; after that point html/template
+	// rejects further Parse calls, so the snapshot sees every template.
+	snapshotOnce sync.Once
+	pristine     *template.Template
+	pristineErr  error
 }
 
 // NewRenderer creates a Renderer with the given templates and layout function.
@@ -745,6 +2833,40 @@ func NewRenderer(tmpl *template.Template, layout LayoutFunc) *Renderer {
 	}
 }
 
+// shared returns the shared template set, first taking the pristine
+// snapshot used by RenderOptions.Funcs. Every path that executes the set
+// must go through shared.
+func (r *Renderer) shared() *template.Template {
+	r.snapshotOnce.Do(func() {
+		r.pristine, r.pristineErr = r.templates.Clone()
+	})
+	return r.templates
+}
+
+// MustLoadTemplates parses the template files matching glob with FuncMap
+// registered, panicking on error. Intended to be called once at startup:
+//
+//	renderer := bf.NewRenderer(bf.MustLoadTemplates("dist/templates/*.tmpl"), layout)
+func MustLoadTemplates(glob string) *template.Template {
+	return MustLoadTemplatesDelims(glob, "", "")
+}
+
+// MustLoadTemplatesDelims is like MustLoadTemplates but parses the files with
+// custom action delimiters, e.g. "[[" and "]]", so the Go templates can share
+// HTML files with a client-side tool that also uses {{ }}. An empty delimiter
+// selects the default. Delimiters only take effect at parse time, which is
+// why they are set here rather than on the Renderer; a set built by hand
+// must call Delims before ParseGlob:
+//
+//	tmpl := template.Must(template.New("").Delims("[[", "]]").Funcs(bf.FuncMap()).ParseGlob(glob))
+//
+// The delimiters carry over to clones, including those made for
+// RenderOptions.Funcs. Template strings passed to bfPortalHTML are parsed
+// separately and always use {{ }}.
+func MustLoadTemplatesDelims(glob, left, right string) *template.Template {
+	return template.Must(template.New("").Delims(left, right).Funcs(FuncMap()).ParseGlob(glob))
+}
+
 // RenderOptions configures a single render call.
 type RenderOptions struct {
 	// ComponentName is the template name to render (required)
@@ -761,42 +2883,157 @@ type RenderOptions struct {
 
 	// Extra holds additional data to pass to the layout
 	Extra map[string]interface{}
+
+	// Head holds extra <head> content for this page (stylesheets, preload
+	// links, OpenGraph meta tags). Identical entries are emitted once.
+	Head []template.HTML
+
+	// Funcs overrides template functions for this render only (e.g., a
+	// locale-specific bf_currency). Every name must already be registered on
+	// the template set: templates are parsed before Funcs applies, so it
+	// cannot introduce new functions. The shared template set is cloned and
+	// the functions layered onto the clone, so other renders are
+	// unaffected. Cloning copies the set's namespace on every call; leave
+	// Funcs nil on hot paths that don't need it.
+	Funcs template.FuncMap
+
+	// ApplyDefaults fills zero-valued props fields from their
+	// bf:"default=..." tags (see ApplyDefaults) before rendering.
+	ApplyDefaults bool
+
+	// Validate checks the props with Validate before rendering and fails the
+	// render if the root or a child component is missing required fields.
+	// Intended for development, where a half-populated props struct would
+	// otherwise render and then fail to hydrate.
+	Validate bool
+
+	// DetectCollisions makes the render fail when two components in the
+	// props tree share a ScopeID (e.g., duplicate todo IDs), which would
+	// otherwise make hydration silently bind the wrong element. The error
+	// names the colliding ID. Intended as a debugging aid for tests and
+	// development.
+	DetectCollisions bool
+
+	// PostProcess, when set, transforms the final page: it runs after the
+	// layout has produced the HTML and before the render method returns or
+	// writes it (e.g., to inject a CSRF meta tag or minify the page). It is
+	// not applied by RenderFragment or Partial, which bypass the layout.
+	PostProcess func(html string) string
 }
 
 // Render renders a component to a full HTML page using the configured layout.
 // Child component props are automatically detected (any slice field with ScopeID/Scripts).
 func (r *Renderer) Render(opts RenderOptions) string {
-	// Create script collector and inject into props
-	scriptCollector := NewScriptCollector()
-	setScriptsField(opts.Props, scriptCollector)
+	ctx, _ := r.buildContext(opts)
+	return r.page(ctx, opts.PostProcess)
+}
+
+// RenderE renders a component like Render but also returns the component
+// template execution error, if any.
+func (r *Renderer) RenderE(opts RenderOptions) (string, error) {
+	ctx, err := r.buildContext(opts)
+	if err != nil {
+		return "", err
+	}
+	return r.page(ctx, opts.PostProcess), nil
+}
+
+// RenderCtx renders a component like RenderE, honoring ctx for
+// cancellation and exposing it to the layout as RenderContext.Ctx.
+//
+// Cancellation is checked at boundaries: before the component template runs
+// and again before the layout is invoked. Template execution itself is not
+// preemptible, so a render already in progress runs to completion before
+// ctx.Err() is reported.
+func (r *Renderer) RenderCtx(ctx context.Context, opts RenderOptions) (string, error) {
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	rc, err := r.buildContext(opts)
+	if err != nil {
+		return "", err
+	}
+	if err := ctx.Err(); err != nil {
+		return "", err
+	}
+	rc.Ctx = ctx
+	return r.page(rc, opts.PostProcess), nil
+}
+
+// RenderMulti renders several independent root components into one page,
+// e.g. a header counter and a sidebar toggle. Each component is its own root
+// (BfIsRoot, so each emits bf-p) and their HTML is concatenated in order into
+// RenderContext.ComponentHTML. Scripts and portals from all components are
+// collected into shared collectors, so a script used by several roots is
+// emitted once.
+//
+// The page-level fields (ComponentName, Title, Heading, Extra, PostProcess)
+// come from the first option; Head entries from every option are merged and deduplicated.
+// Returns the first template execution error, if any.
+func (r *Renderer) RenderMulti(opts []RenderOptions) (string, error) {
+	if len(opts) == 0 {
+		return "", fmt.Errorf("bf: RenderMulti: no components to render")
+	}
 
-	// Create portal collector and inject into props
+	scriptCollector := NewScriptCollector()
 	portalCollector := NewPortalCollector()
-	setPortalsField(opts.Props, portalCollector)
 
-	// Auto-detect and process child component props (slices)
-	childSlices := findChildComponentSlices(opts.Props)
-	for _, slice := range childSlices {
-		setScriptsOnSlice(slice, scriptCollector)
-		setPortalsOnSlice(slice, portalCollector)
-		setBoolOnSlice(slice, "BfIsChild", true)
+	var componentBuf strings.Builder
+	for _, o := range opts {
+		if err := r.executeComponent(&componentBuf, o, scriptCollector, portalCollector); err != nil {
+			return "", err
+		}
 	}
 
-	// Auto-detect and process single child component props
-	singleChildren := findSingleChildComponents(opts.Props)
-	for _, child := range singleChildren {
-		setScriptsOnSingle(child, scriptCollector)
-		setPortalsOnSingle(child, portalCollector)
-		setBoolField(child, "BfIsChild", true)
+	page := opts[0]
+	page.Head = nil
+	for _, o := range opts {
+		page.Head = append(page.Head, o.Head...)
 	}
+	ctx := newRenderContext(page, template.HTML(componentBuf.String()), scriptCollector, portalCollector)
+	return r.page(ctx, page.PostProcess), nil
+}
 
-	// Mark the root component so BfPropsAttr emits bf-p only for it
-	setBoolField(opts.Props, "BfIsRoot", true)
+// RenderTo renders a component like Render but writes the page to w (e.g.,
+// an http.ResponseWriter) instead of returning it.
+//
+// Portals and scripts are only known after the component template runs, so
+// the component is still executed first and the RenderContext fully built
+// before the layout is invoked; the layout output is then written to w in
+// one pass. Returns the template execution error, if any (the page is still
+// written with whatever the template produced), or the write error.
+func (r *Renderer) RenderTo(w io.Writer, opts RenderOptions) error {
+	ctx, execErr := r.buildContext(opts)
+	if _, err := io.WriteString(w, r.page(ctx, opts.PostProcess)); err != nil {
+		return err
+	}
+	return execErr
+}
+
+// page runs the layout and then postProcess, if set, on its output.
+func (r *Renderer) page(ctx *RenderContext, postProcess func(string) string) string {
+	html := r.layout(ctx)
+	if postProcess != nil {
+		html = postProcess(html)
+	}
+	return html
+}
+
+// buildContext prepares props (collectors, child markers), executes the
+// component template, and assembles the RenderContext for the layout.
+func (r *Renderer) buildContext(opts RenderOptions) (*RenderContext, error) {
+	scriptCollector := NewScriptCollector()
+	portalCollector := NewPortalCollector()
 
 	// Render the component template
 	var componentBuf strings.Builder
-	r.templates.ExecuteTemplate(&componentBuf, opts.ComponentName, opts.Props)
+	execErr := r.executeComponent(&componentBuf, opts, scriptCollector, portalCollector)
+
+	return newRenderContext(opts, template.HTML(componentBuf.String()), scriptCollector, portalCollector), execErr
+}
 
+// newRenderContext assembles the RenderContext for the layout.
+func newRenderContext(opts RenderOptions, componentHTML template.HTML, scriptCollector *ScriptCollector, portalCollector *PortalCollector) *RenderContext {
 	// Determine title (default: "{ComponentName} - BarefootJS")
 	title := opts.Title
 	if title == "" {
@@ -810,15 +3047,296 @@ func (r *Renderer) Render(opts RenderOptions) string {
 	ctx := &RenderContext{
 		ComponentName: opts.ComponentName,
 		Props:         opts.Props,
-		ComponentHTML: template.HTML(componentBuf.String()),
+		ComponentHTML: componentHTML,
 		Portals:       portalCollector.Render(),
 		Scripts:       BfScripts(scriptCollector),
 		Title:         title,
 		Heading:       heading,
 		Extra:         opts.Extra,
+		Head:          joinHead(opts.Head),
+		Ctx:           context.Background(),
+	}
+
+	return ctx
+}
+
+// joinHead joins head entries with newlines, dropping empty and duplicate
+// entries while keeping first-seen order.
+func joinHead(entries []template.HTML) template.HTML {
+	seen := make(map[template.HTML]bool, len(entries))
+	var b strings.Builder
+	for _, e := range entries {
+		if e == "" || seen[e] {
+			continue
+		}
+		seen[e] = true
+		if b.Len() > 0 {
+			b.WriteByte('\n')
+		}
+		b.WriteString(string(e))
+	}
+	return template.HTML(b.String())
+}
+
+// RenderFragment renders only the component template, without the layout,
+// for partial page updates (e.g., an HTMX swap in response to a POST).
+//
+// Collectors and child markers are injected exactly as in Render and props
+// is marked as the root, so the fragment matches a component that was
+// rendered as the page root: bf-s="ScopeID" plus its bf-p props. To
+// re-render a component that was a child on the initial page (e.g., one todo
+// item of a list), use RenderFragmentChild instead.
+//
+// For the client runtime to re-bind the swapped-in markup, the props must use
+// the same ScopeID values as the initial render; a fresh ScopeID produces a
+// new, unrelated scope. Collected scripts and portals are discarded: the page
+// is expected to have loaded them already.
+func (r *Renderer) RenderFragment(componentName string, props interface{}) (template.HTML, error) {
+	prepareProps(props, NewScriptCollector(), NewPortalCollector())
+	return r.executeFragment(componentName, props)
+}
+
+// RenderFragmentChild renders a fragment like RenderFragment, but marks props
+// as a child component (BfIsChild) instead of the root, so the output matches
+// the component as it was rendered inside its parent on the initial page:
+// bf-s="~ScopeID" and no bf-p attribute.
+func (r *Renderer) RenderFragmentChild(componentName string, props interface{}) (template.HTML, error) {
+	injectCollectors(props, NewScriptCollector(), NewPortalCollector())
+	setBoolField(props, "BfIsRoot", false)
+	setBoolField(props, "BfIsChild", true)
+	return r.executeFragment(componentName, props)
+}
+
+// executeFragment executes the component template for an already prepared
+// fragment.
+func (r *Renderer) executeFragment(componentName string, props interface{}) (template.HTML, error) {
+	var buf strings.Builder
+	if err := r.shared().ExecuteTemplate(&buf, componentName, props); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
+
+// Partial executes the named template with data and returns its output, for
+// layouts that stitch several sections into one page. Unlike RenderFragment
+// it leaves data untouched: no collectors are injected and nothing is marked
+// as a root or child, so partials should not rely on bfScripts or emit bf-p.
+func (r *Renderer) Partial(name string, data interface{}) (template.HTML, error) {
+	var buf strings.Builder
+	if err := r.shared().ExecuteTemplate(&buf, name, data); err != nil {
+		return "", err
+	}
+	return template.HTML(buf.String()), nil
+}
+
+// prepareProps injects the script/portal collectors into props and its
+// child components, and marks props as the root component. Returns the child
+// component props found, in walk order.
+func prepareProps(props interface{}, scriptCollector *ScriptCollector, portalCollector *PortalCollector) []interface{} {
+	children := injectCollectors(props, scriptCollector, portalCollector)
+
+	// Mark the root component so BfPropsAttr emits bf-p only for it
+	setBoolField(props, "BfIsRoot", true)
+	return children
+}
+
+// injectCollectors injects the script/portal collectors into props and its
+// child components, marking the children with BfIsChild. Returns the child
+// component props found, in walk order.
+func injectCollectors(props interface{}, scriptCollector *ScriptCollector, portalCollector *PortalCollector) []interface{} {
+	// Inject collectors into props
+	setScriptsField(props, scriptCollector)
+	setPortalsField(props, portalCollector)
+
+	// Auto-detect and process child component props (slices, maps, single
+	// fields), recursing into grandchildren
+	var children []interface{}
+	visited := map[visitKey]bool{}
+	markVisited(props, visited)
+	injectChildComponents(props, scriptCollector, portalCollector, visited, &children)
+	return children
+}
+
+// ApplyDefaults sets each top-level field of props that is still at its zero
+// value to the default declared in its bf tag:
+//
+//	type ListProps struct {
+//		PageSize int    `json:"pageSize" bf:"default=20"`
+//		Sort     string `json:"sort" bf:"default=newest"`
+//	}
+//
+// Options in the bf tag are comma-separated (see parseBfTag), so a field can
+// be both server-only and defaulted: bf:"server,default=20". "default=" must
+// come last; everything after it is the value, parsed for string, int, uint,
+// float and bool fields. Fields that were set explicitly are left alone, so
+// a default can only be overridden by a non-zero value (an explicit false or
+// 0 is indistinguishable from unset). props must be a non-nil pointer to a
+// struct. Returns an error naming the field if a default cannot be parsed or
+// the field type is unsupported.
+func ApplyDefaults(props interface{}) error {
+	rv := reflect.ValueOf(props)
+	if rv.Kind() != reflect.Ptr || rv.IsNil() || rv.Elem().Kind() != reflect.Struct {
+		return fmt.Errorf("bf: ApplyDefaults: %T is not a non-nil pointer to struct", props)
+	}
+	rv = rv.Elem()
+	t := rv.Type()
+
+	for i := 0; i < t.NumField(); i++ {
+		tag := parseBfTag(t.Field(i))
+		if !tag.hasDefault {
+			continue
+		}
+		field := rv.Field(i)
+		if !field.CanSet() || !field.IsZero() {
+			continue
+		}
+		if err := setFromString(field, tag.def); err != nil {
+			return fmt.Errorf("bf: ApplyDefaults: field %s: %w", t.Field(i).Name, err)
+		}
+	}
+	return nil
+}
+
+// setFromString parses s into field according to the field's kind.
+func setFromString(field reflect.Value, s string) error {
+	switch field.Kind() {
+	case reflect.String:
+		field.SetString(s)
+	case reflect.Int, reflect.Int8, reflect.Int16, reflect.Int32, reflect.Int64:
+		n, err := strconv.ParseInt(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetInt(n)
+	case reflect.Uint, reflect.Uint8, reflect.Uint16, reflect.Uint32, reflect.Uint64:
+		n, err := strconv.ParseUint(s, 10, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetUint(n)
+	case reflect.Float32, reflect.Float64:
+		f, err := strconv.ParseFloat(s, field.Type().Bits())
+		if err != nil {
+			return err
+		}
+		field.SetFloat(f)
+	case reflect.Bool:
+		b, err := strconv.ParseBool(s)
+		if err != nil {
+			return err
+		}
+		field.SetBool(b)
+	default:
+		return fmt.Errorf("unsupported default type %s", field.Type())
+	}
+	return nil
+}
+
+// Validate checks that props is ready to render and hydrate: the root must
+// be a struct (or pointer to one) with a non-empty ScopeID and a Scripts
+// field, and every detected child component must have a non-empty ScopeID.
+// The returned error lists every problem found, e.g.:
+//
+//	bf: invalid props: root (*TodoAppProps): missing ScopeID; child 2 (*TodoItemProps): missing ScopeID
+//
+// Children are numbered in walk order starting at 1. props is not modified.
+func Validate(props interface{}) error {
+	rv := reflect.ValueOf(props)
+	if rv.Kind() == reflect.Ptr && !rv.IsNil() {
+		rv = rv.Elem()
+	}
+	if rv.Kind() != reflect.Struct {
+		return fmt.Errorf("bf: invalid props: %T is not a struct or pointer to struct", props)
+	}
+
+	var problems []string
+	var missing []string
+	if getStringField(props, "ScopeID") == "" {
+		missing = append(missing, "missing ScopeID")
+	}
+	if !rv.FieldByName("Scripts").IsValid() {
+		missing = append(missing, "missing Scripts field")
+	}
+	if len(missing) > 0 {
+		problems = append(problems, fmt.Sprintf("root (%T): %s", props, strings.Join(missing, ", ")))
+	}
+
+	n := 0
+	visited := map[visitKey]bool{}
+	markVisited(props, visited)
+	walkChildComponents(props, visited, func(child interface{}) {
+		n++
+		if getStringField(child, "ScopeID") == "" {
+			problems = append(problems, fmt.Sprintf("child %d (%T): missing ScopeID", n, child))
+		}
+	})
+
+	if len(problems) > 0 {
+		return fmt.Errorf("bf: invalid props: %s", strings.Join(problems, "; "))
+	}
+	return nil
+}
+
+// checkScopeIDCollisions returns an error naming the first ScopeID shared by
+// two of the given props. Empty ScopeIDs are ignored.
+func checkScopeIDCollisions(props []interface{}) error {
+	seen := make(map[string]bool, len(props))
+	for _, p := range props {
+		id := getStringField(p, "ScopeID")
+		if id == "" {
+			continue
+		}
+		if seen[id] {
+			return fmt.Errorf("bf: duplicate ScopeID %q in props tree", id)
+		}
+		seen[id] = true
+	}
+	return nil
+}
+
+// executeComponent prepares opts.Props with the given collectors and
+// executes the component template into w.
+func (r *Renderer) executeComponent(w io.Writer, opts RenderOptions, scriptCollector *ScriptCollector, portalCollector *PortalCollector) error {
+	if opts.ApplyDefaults {
+		if err := ApplyDefaults(opts.Props); err != nil {
+			return err
+		}
+	}
+	if opts.Validate {
+		if err := Validate(opts.Props); err != nil {
+			return err
+		}
 	}
+	children := prepareProps(opts.Props, scriptCollector, portalCollector)
+	if opts.DetectCollisions {
+		if err := checkScopeIDCollisions(append([]interface{}{opts.Props}, children...)); err != nil {
+			return err
+		}
+	}
+
+	tmpl, err := r.templatesFor(opts.Funcs)
+	if err != nil {
+		return err
+	}
+	return tmpl.ExecuteTemplate(w, opts.ComponentName, opts.Props)
+}
 
-	return r.layout(ctx)
+// templatesFor returns the template set to execute: the shared set, or a
+// per-render clone with funcs layered on when funcs is non-empty. The clone
+// fails if the set was executed before it was handed to NewRenderer.
+func (r *Renderer) templatesFor(funcs template.FuncMap) (*template.Template, error) {
+	shared := r.shared()
+	if len(funcs) == 0 {
+		return shared, nil
+	}
+	if r.pristineErr != nil {
+		return nil, fmt.Errorf("bf: RenderOptions.Funcs: template set was executed before NewRenderer: %w", r.pristineErr)
+	}
+	clone, err := r.pristine.Clone()
+	if err != nil {
+		return nil, fmt.Errorf("bf: RenderOptions.Funcs: %w", err)
+	}
+	return clone.Funcs(funcs), nil
 }
 
 // setScriptsField sets the Scripts field on a struct using reflection.
@@ -898,6 +3416,8 @@ func getStringField(v interface{}, fieldName string) string {
 
 // findChildComponentSlices finds slice fields containing child component props.
 // Child props are identified by having ScopeID and Scripts fields.
+// Both value ([]T) and pointer ([]*T) element slices are detected; detection
+// uses the element type, so a nil first element does not hide the slice.
 func findChildComponentSlices(props interface{}) []interface{} {
 	var result []interface{}
 
@@ -909,24 +3429,12 @@ func findChildComponentSlices(props interface{}) []interface{} {
 		return result
 	}
 
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
+	for _, field := range componentFields(val) {
 		if field.Kind() != reflect.Slice || field.Len() == 0 {
 			continue
 		}
 
-		elem := field.Index(0)
-		if elem.Kind() == reflect.Ptr {
-			elem = elem.Elem()
-		}
-		if elem.Kind() != reflect.Struct {
-			continue
-		}
-
-		hasScopeID := elem.FieldByName("ScopeID").IsValid()
-		hasScripts := elem.FieldByName("Scripts").IsValid()
-
-		if hasScopeID && hasScripts {
+		if isChildComponentType(field.Type().Elem()) {
 			result = append(result, field.Interface())
 		}
 	}
@@ -934,48 +3442,121 @@ func findChildComponentSlices(props interface{}) []interface{} {
 	return result
 }
 
-// setScriptsOnSlice sets Scripts on all items in a slice.
-func setScriptsOnSlice(slice interface{}, collector *ScriptCollector) {
-	val := reflect.ValueOf(slice)
-	if val.Kind() != reflect.Slice {
-		return
-	}
-	for i := 0; i < val.Len(); i++ {
-		item := val.Index(i)
-		if item.Kind() == reflect.Ptr {
-			item = item.Elem()
-		}
-		if item.Kind() == reflect.Struct {
-			field := item.FieldByName("Scripts")
-			if field.IsValid() && field.CanSet() {
-				field.Set(reflect.ValueOf(collector))
+// componentFields returns the fields of the struct val to inspect for child
+// components. Anonymous embedded structs (and non-nil pointers to them) are
+// walked into rather than returned, so child fields declared on an embedded
+// base (e.g., a shared BaseProps) are found as if declared on val, and the
+// base itself, whose ScopeID and Scripts are promoted to val, is never
+// mistaken for a child.
+func componentFields(val reflect.Value) []reflect.Value {
+	return appendComponentFields(nil, val, map[reflect.Type]bool{})
+}
+
+// appendComponentFields appends the fields of val to fields, descending into
+// embedded structs. path holds the embedded types on the current path and
+// stops self-referential pointer embedding (type Node struct{ *Node }).
+func appendComponentFields(fields []reflect.Value, val reflect.Value, path map[reflect.Type]bool) []reflect.Value {
+	t := val.Type()
+	path[t] = true
+	defer delete(path, t)
+
+	for i := 0; i < val.NumField(); i++ {
+		field := val.Field(i)
+		if t.Field(i).Anonymous {
+			embedded := field
+			if embedded.Kind() == reflect.Ptr && !embedded.IsNil() {
+				embedded = embedded.Elem()
+			}
+			if embedded.Kind() == reflect.Struct {
+				if !path[embedded.Type()] {
+					fields = appendComponentFields(fields, embedded, path)
+				}
+				continue
 			}
 		}
+		fields = append(fields, field)
 	}
+	return fields
 }
 
-// setBoolOnSlice sets a bool field on all items in a slice.
-func setBoolOnSlice(slice interface{}, fieldName string, val bool) {
-	v := reflect.ValueOf(slice)
-	if v.Kind() != reflect.Slice {
-		return
+// isChildComponentType reports whether t (or the type t points to) is a
+// struct with ScopeID and Scripts fields.
+func isChildComponentType(t reflect.Type) bool {
+	if t.Kind() == reflect.Ptr {
+		t = t.Elem()
 	}
-	for i := 0; i < v.Len(); i++ {
-		item := v.Index(i)
-		if item.Kind() == reflect.Ptr {
-			item = item.Elem()
-		}
-		if item.Kind() == reflect.Struct {
-			field := item.FieldByName(fieldName)
-			if field.IsValid() && field.CanSet() && field.Kind() == reflect.Bool {
-				field.SetBool(val)
-			}
+	if t.Kind() != reflect.Struct {
+		return false
+	}
+	_, hasScopeID := t.FieldByName("ScopeID")
+	_, hasScripts := t.FieldByName("Scripts")
+	return hasScopeID && hasScripts
+}
+
+// injectChildComponents walks the child component props of props (slice,
+// map and single struct fields) and sets Scripts, Portals and BfIsChild on
+// each child, then recurses so grandchildren at any depth are processed.
+// visited holds the addresses of props already processed and guards against
+// cycles in self-referential props. Each child processed is appended to
+// children.
+func injectChildComponents(props interface{}, scripts *ScriptCollector, portals *PortalCollector, visited map[visitKey]bool, children *[]interface{}) {
+	walkChildComponents(props, visited, func(child interface{}) {
+		setScriptsOnSingle(child, scripts)
+		setPortalsOnSingle(child, portals)
+		setBoolField(child, "BfIsChild", true)
+		*children = append(*children, child)
+	})
+}
+
+// walkChildComponents calls fn with each child component of props (slice,
+// map and single struct fields), depth-first, recursing after fn returns.
+// Children already in visited are skipped.
+func walkChildComponents(props interface{}, visited map[visitKey]bool, fn func(child interface{})) {
+	visit := func(child interface{}) {
+		if !markVisited(child, visited) {
+			return
 		}
+		fn(child)
+		walkChildComponents(child, visited, fn)
+	}
+
+	for _, slice := range findChildComponentSlices(props) {
+		forEachSliceItem(slice, visit)
+	}
+	for _, m := range findChildComponentMaps(props) {
+		updateMapValues(m, visit)
+	}
+	for _, child := range findSingleChildComponents(props) {
+		visit(child)
+	}
+}
+
+// visitKey identifies a props struct during the child walk. The type is part
+// of the key because a struct stored as the first field of its parent shares
+// the parent's address.
+type visitKey struct {
+	ptr uintptr
+	typ reflect.Type
+}
+
+// markVisited records the struct pointer v in visited. Returns false if v is
+// nil, not a pointer, or was already visited.
+func markVisited(v interface{}, visited map[visitKey]bool) bool {
+	val := reflect.ValueOf(v)
+	if val.Kind() != reflect.Ptr || val.IsNil() {
+		return false
+	}
+	key := visitKey{ptr: val.Pointer(), typ: val.Type()}
+	if visited[key] {
+		return false
 	}
+	visited[key] = true
+	return true
 }
 
-// setPortalsOnSlice sets Portals on all items in a slice.
-func setPortalsOnSlice(slice interface{}, collector *PortalCollector) {
+// forEachSliceItem calls fn with a pointer to each struct element of slice.
+// Pointer elements are passed through; nil elements are skipped.
+func forEachSliceItem(slice interface{}, fn func(child interface{})) {
 	val := reflect.ValueOf(slice)
 	if val.Kind() != reflect.Slice {
 		return
@@ -983,18 +3564,17 @@ func setPortalsOnSlice(slice interface{}, collector *PortalCollector) {
 	for i := 0; i < val.Len(); i++ {
 		item := val.Index(i)
 		if item.Kind() == reflect.Ptr {
-			item = item.Elem()
+			if !item.IsNil() {
+				fn(item.Interface())
+			}
+			continue
 		}
 		if item.Kind() == reflect.Struct {
-			field := item.FieldByName("Portals")
-			if field.IsValid() && field.CanSet() {
-				field.Set(reflect.ValueOf(collector))
-			}
+			fn(item.Addr().Interface())
 		}
 	}
 }
 
-
 // findSingleChildComponents finds single struct fields containing child component props.
 // Child props are identified by having ScopeID and Scripts fields.
 func findSingleChildComponents(props interface{}) []interface{} {
@@ -1008,9 +3588,7 @@ func findSingleChildComponents(props interface{}) []interface{} {
 		return result
 	}
 
-	for i := 0; i < val.NumField(); i++ {
-		field := val.Field(i)
-
+	for _, field := range componentFields(val) {
 		// Handle pointer to struct
 		if field.Kind() == reflect.Ptr {
 			if field.IsNil() {
@@ -1035,6 +3613,58 @@ func findSingleChildComponents(props interface{}) []interface{} {
 	return result
 }
 
+// findChildComponentMaps finds map fields whose values are child component
+// props (map[K]T or map[K]*T where T has ScopeID and Scripts fields).
+func findChildComponentMaps(props interface{}) []interface{} {
+	var result []interface{}
+
+	val := reflect.ValueOf(props)
+	if val.Kind() == reflect.Ptr {
+		val = val.Elem()
+	}
+	if val.Kind() != reflect.Struct {
+		return result
+	}
+
+	for _, field := range componentFields(val) {
+		if field.Kind() != reflect.Map || field.Len() == 0 {
+			continue
+		}
+		if isChildComponentType(field.Type().Elem()) {
+			result = append(result, field.Interface())
+		}
+	}
+
+	return result
+}
+
+// updateMapValues calls fn with a pointer to each struct value in map m.
+// Map values are not addressable, so value-typed entries are copied, passed
+// to fn, and written back; pointer entries are passed through directly.
+func updateMapValues(m interface{}, fn func(child interface{})) {
+	val := reflect.ValueOf(m)
+	if val.Kind() != reflect.Map {
+		return
+	}
+	iter := val.MapRange()
+	for iter.Next() {
+		item := iter.Value()
+		if item.Kind() == reflect.Ptr {
+			if !item.IsNil() {
+				fn(item.Interface())
+			}
+			continue
+		}
+		if item.Kind() != reflect.Struct {
+			continue
+		}
+		copied := reflect.New(item.Type())
+		copied.Elem().Set(item)
+		fn(copied.Interface())
+		val.SetMapIndex(iter.Key(), copied.Elem())
+	}
+}
+
 // setScriptsOnSingle sets Scripts on a single struct child component.
 func setScriptsOnSingle(child interface{}, collector *ScriptCollector) {
 	val := reflect.ValueOf(child)
@@ -1130,6 +3760,50 @@ func toInt(v any) int {
 	}
 }
 
+// isTruthy reports whether v is truthy under JavaScript semantics:
+// false, 0, NaN, "", nil, and nil pointers/slices/maps/interfaces are falsy;
+// everything else (including empty non-nil slices and maps) is truthy.
+func isTruthy(v any) bool {
+	if v == nil {
+		return false
+	}
+	switch b := v.(type) {
+	case bool:
+		return b
+	case string:
+		return b != ""
+	}
+	if isIntLike(v) {
+		return toFloat64(v) != 0
+	}
+	switch n := v.(type) {
+	case float32:
+		return n != 0 && !math.IsNaN(float64(n))
+	case float64:
+		return n != 0 && !math.IsNaN(n)
+	}
+
+	rv := reflect.ValueOf(v)
+	switch rv.Kind() {
+	case reflect.Ptr, reflect.Slice, reflect.Map, reflect.Interface, reflect.Func, reflect.Chan:
+		return !rv.IsNil()
+	case reflect.String:
+		return rv.Len() > 0
+	case reflect.Bool:
+		return rv.Bool()
+	}
+	return true
+}
+
+func isNumeric(v any) bool {
+	switch v.(type) {
+	case float32, float64:
+		return true
+	default:
+		return isIntLike(v)
+	}
+}
+
 func isIntLike(v any) bool {
 	switch v.(type) {
 	case int, int8, int16, int32, int64, uint, uint8, uint16, uint32, uint64:
@@ -1,8 +1,21 @@
 package bf
 
 import (
+	"context"
+	"encoding/json"
+	"fmt"
 	"html/template"
+	"io"
+	"math"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
 	"testing"
+	"time"
 )
 
 func TestAdd(t *testing.T) {
@@ -78,6 +91,31 @@ func TestDiv(t *testing.T) {
 	}
 }
 
+func TestIDiv(t *testing.T) {
+	tests := []struct {
+		a, b any
+		want int
+	}{
+		{10, 2, 5},
+		{7, 2, 3},
+		{-7, 2, -4}, // Floors like Math.floor(-7 / 2)
+		{7, -2, -4},
+		{-8, -2, 4},
+		{int64(9), uint8(4), 2},
+		{10, 0, 0},    // Division by zero returns 0
+		{7, 2.5, 2},   // Math.floor(7 / 2.5), not 7 / 2
+		{-0.5, 1, -1}, // Math.floor(-0.5), not 0 / 1
+		{7.5, 2, 3},
+		{1, 0.0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := IDiv(tt.a, tt.b); got != tt.want {
+			t.Errorf("IDiv(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.want)
+		}
+	}
+}
+
 func TestMod(t *testing.T) {
 	tests := []struct {
 		a, b any
@@ -114,249 +152,1932 @@ func TestNeg(t *testing.T) {
 	}
 }
 
-func TestLower(t *testing.T) {
-	if got := Lower("HELLO"); got != "hello" {
-		t.Errorf("Lower(HELLO) = %v, want hello", got)
+func TestMaxNMinN(t *testing.T) {
+	tests := []struct {
+		name    string
+		vals    []any
+		wantMax any
+		wantMin any
+	}{
+		{"three ints", []any{3, 9, -2}, 9, -2},
+		{"mixed int and float", []any{1, 2.5, 2}, 2.5, 1.0},
+		{"mixed int types", []any{int64(4), uint8(7), 5}, 7, 4},
+		{"single", []any{42}, 42, 42},
+		{"empty", nil, 0, 0},
 	}
-}
 
-func TestUpper(t *testing.T) {
-	if got := Upper("hello"); got != "HELLO" {
-		t.Errorf("Upper(hello) = %v, want HELLO", got)
+	for _, tt := range tests {
+		if got := MaxN(tt.vals...); got != tt.wantMax {
+			t.Errorf("MaxN(%s) = %v (%T), want %v (%T)", tt.name, got, got, tt.wantMax, tt.wantMax)
+		}
+		if got := MinN(tt.vals...); got != tt.wantMin {
+			t.Errorf("MinN(%s) = %v (%T), want %v (%T)", tt.name, got, got, tt.wantMin, tt.wantMin)
+		}
 	}
 }
 
-func TestTrim(t *testing.T) {
-	if got := Trim("  hello  "); got != "hello" {
-		t.Errorf("Trim(  hello  ) = %v, want hello", got)
+func TestComparison_MixedNumeric(t *testing.T) {
+	tests := []struct {
+		a, b                     any
+		eq, ne, gt, lt, gte, lte bool
+	}{
+		{3, 2.5, false, true, true, false, true, false},
+		{2.5, 3, false, true, false, true, false, true},
+		{2, 2.0, true, false, false, false, true, true},
+		{int64(10), uint8(10), true, false, false, false, true, true},
+		{float32(1.5), 2, false, true, false, true, false, true},
+	}
+
+	for _, tt := range tests {
+		if got := Eq(tt.a, tt.b); got != tt.eq {
+			t.Errorf("Eq(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.eq)
+		}
+		if got := Ne(tt.a, tt.b); got != tt.ne {
+			t.Errorf("Ne(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.ne)
+		}
+		if got := Gt(tt.a, tt.b); got != tt.gt {
+			t.Errorf("Gt(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.gt)
+		}
+		if got := Lt(tt.a, tt.b); got != tt.lt {
+			t.Errorf("Lt(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.lt)
+		}
+		if got := Gte(tt.a, tt.b); got != tt.gte {
+			t.Errorf("Gte(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.gte)
+		}
+		if got := Lte(tt.a, tt.b); got != tt.lte {
+			t.Errorf("Lte(%v, %v) = %v, want %v", tt.a, tt.b, got, tt.lte)
+		}
 	}
 }
 
-func TestContains(t *testing.T) {
-	if !Contains("hello world", "world") {
-		t.Error("Contains(hello world, world) should be true")
+func TestComparison_Strings(t *testing.T) {
+	if !Lt("apple", "banana") {
+		t.Error("Lt(apple, banana) should be true")
 	}
-	if Contains("hello world", "foo") {
-		t.Error("Contains(hello world, foo) should be false")
+	if !Gt("b", "a") {
+		t.Error("Gt(b, a) should be true")
+	}
+	if !Gte("same", "same") || !Lte("same", "same") {
+		t.Error("Gte/Lte on equal strings should be true")
+	}
+	if !Eq("x", "x") || Eq("x", "y") {
+		t.Error("Eq should compare strings by content")
+	}
+	if !Ne("x", "y") {
+		t.Error("Ne(x, y) should be true")
+	}
+	// Numeric strings compare lexically, like JS "10" < "9"
+	if !Lt("10", "9") {
+		t.Error("Lt(\"10\", \"9\") should be true for string comparison")
 	}
 }
 
-func TestJoin(t *testing.T) {
-	items := []string{"a", "b", "c"}
-	if got := Join(items, ", "); got != "a, b, c" {
-		t.Errorf("Join(%v, ', ') = %v, want 'a, b, c'", items, got)
+func TestTernary(t *testing.T) {
+	if got := Ternary(true, "done", "pending"); got != "done" {
+		t.Errorf("Ternary(true) = %v, want done", got)
+	}
+	if got := Ternary(false, "done", "pending"); got != "pending" {
+		t.Errorf("Ternary(false) = %v, want pending", got)
 	}
 }
 
-func TestLen(t *testing.T) {
+func TestCond(t *testing.T) {
+	var nilSlice []int
 	tests := []struct {
-		v    any
-		want int
+		cond any
+		want any
 	}{
-		{[]int{1, 2, 3}, 3},
-		{[]string{}, 0},
-		{"hello", 5},
-		{nil, 0},
-		{map[string]int{"a": 1, "b": 2}, 2},
+		{true, "yes"},
+		{false, "no"},
+		{"text", "yes"},
+		{"", "no"},
+		{1, "yes"},
+		{0, "no"},
+		{0.0, "no"},
+		{-2.5, "yes"},
+		{nil, "no"},
+		{nilSlice, "no"},
+		{[]int{}, "yes"},
 	}
 
 	for _, tt := range tests {
-		got := Len(tt.v)
+		got := Cond(tt.cond, "yes", "no")
 		if got != tt.want {
-			t.Errorf("Len(%v) = %v, want %v", tt.v, got, tt.want)
+			t.Errorf("Cond(%#v) = %v, want %v", tt.cond, got, tt.want)
 		}
 	}
 }
 
-func TestAt(t *testing.T) {
-	items := []string{"a", "b", "c", "d"}
-
+func TestTruthy(t *testing.T) {
+	var nilPtr *int
 	tests := []struct {
-		index int
-		want  any
+		v    any
+		want bool
 	}{
-		{0, "a"},
-		{1, "b"},
-		{-1, "d"},  // Last element
-		{-2, "c"},  // Second to last
-		{10, nil},  // Out of bounds
-		{-10, nil}, // Out of bounds
+		{0, false},
+		{int64(0), false},
+		{0.0, false},
+		{"", false},
+		{nil, false},
+		{nilPtr, false},
+		{false, false},
+		{1, true},
+		{"0", true},
+		{true, true},
+		{map[string]int{}, true},
+		{struct{}{}, true},
 	}
 
 	for _, tt := range tests {
-		got := At(items, tt.index)
-		if got != tt.want {
-			t.Errorf("At(items, %v) = %v, want %v", tt.index, got, tt.want)
+		if got := Truthy(tt.v); got != tt.want {
+			t.Errorf("Truthy(%#v) = %v, want %v", tt.v, got, tt.want)
+		}
+		if got := Not(tt.v); got != !tt.want {
+			t.Errorf("Not(%#v) = %v, want %v", tt.v, got, !tt.want)
 		}
 	}
 }
 
-func TestIncludes(t *testing.T) {
-	items := []int{1, 2, 3, 4, 5}
-
-	if !Includes(items, 3) {
-		t.Error("Includes(items, 3) should be true")
+func TestToBool(t *testing.T) {
+	var nilSlice []int
+	var nilMap map[string]int
+	tests := []struct {
+		name string
+		v    any
+		want bool
+	}{
+		{"nil", nil, false},
+		{"false", false, false},
+		{"int zero", 0, false},
+		{"uint8 zero", uint8(0), false},
+		{"float zero", 0.0, false},
+		{"NaN", math.NaN(), false},
+		{"empty string", "", false},
+		{"nil slice", nilSlice, false},
+		{"nil map", nilMap, false},
+		{"true", true, true},
+		{"negative", -1, true},
+		{"string", "false", true},
+		{"empty slice", []int{}, true},
+		{"empty map", map[string]int{}, true},
+		{"struct", struct{}{}, true},
 	}
-	if Includes(items, 10) {
-		t.Error("Includes(items, 10) should be false")
+
+	for _, tt := range tests {
+		if got := ToBool(tt.v); got != tt.want {
+			t.Errorf("ToBool(%s) = %v, want %v", tt.name, got, tt.want)
+		}
 	}
 }
 
-func TestFirst(t *testing.T) {
-	items := []string{"a", "b", "c"}
-	if got := First(items); got != "a" {
-		t.Errorf("First(items) = %v, want 'a'", got)
+func TestToNumber(t *testing.T) {
+	tests := []struct {
+		name string
+		v    any
+		want float64
+	}{
+		{"int", 42, 42},
+		{"float", 2.5, 2.5},
+		{"numeric string", "12.5", 12.5},
+		{"padded string", " -3 ", -3},
+		{"exponent string", "1e3", 1000},
+		{"empty string", "", 0},
+		{"garbage", "abc", 0},
+		{"trailing garbage", "12px", 0},
+		{"NaN string", "NaN", 0},
+		{"true", true, 1},
+		{"false", false, 0},
+		{"nil", nil, 0},
+		{"struct", struct{}{}, 0},
 	}
 
-	empty := []string{}
-	if got := First(empty); got != nil {
-		t.Errorf("First(empty) = %v, want nil", got)
+	for _, tt := range tests {
+		if got := ToNumber(tt.v); got != tt.want {
+			t.Errorf("ToNumber(%s) = %v, want %v", tt.name, got, tt.want)
+		}
 	}
 }
 
-func TestLast(t *testing.T) {
-	items := []string{"a", "b", "c"}
-	if got := Last(items); got != "c" {
-		t.Errorf("Last(items) = %v, want 'c'", got)
+func TestToString(t *testing.T) {
+	tests := []struct {
+		v    any
+		want string
+	}{
+		{"hi", "hi"},
+		{42, "42"},
+		{int64(-7), "-7"},
+		{2.5, "2.5"},
+		{2.0, "2"},
+		{true, "true"},
+		{nil, ""},
 	}
 
-	empty := []string{}
-	if got := Last(empty); got != nil {
-		t.Errorf("Last(empty) = %v, want nil", got)
+	for _, tt := range tests {
+		if got := ToString(tt.v); got != tt.want {
+			t.Errorf("ToString(%v) = %q, want %q", tt.v, got, tt.want)
+		}
 	}
 }
 
-// =============================================================================
-// Find / FindIndex Tests
-// =============================================================================
+func TestAnd(t *testing.T) {
+	tests := []struct {
+		values []any
+		want   any
+	}{
+		{[]any{1, "a", true}, true},
+		{[]any{1, 0, "a"}, 0},
+		{[]any{"x", "", nil}, ""},
+		{[]any{"x", 2.5}, 2.5},
+		{[]any{}, true},
+	}
 
-type findItem struct {
-	Id   int
-	Name string
-	Done bool
+	for _, tt := range tests {
+		if got := And(tt.values...); got != tt.want {
+			t.Errorf("And(%v) = %#v, want %#v", tt.values, got, tt.want)
+		}
+	}
 }
 
-func TestFind_ByBooleanField(t *testing.T) {
-	items := []findItem{
-		{Id: 1, Name: "A", Done: false},
-		{Id: 2, Name: "B", Done: true},
-		{Id: 3, Name: "C", Done: false},
+func TestOr(t *testing.T) {
+	tests := []struct {
+		values []any
+		want   any
+	}{
+		{[]any{0, "", "fallback", "later"}, "fallback"},
+		{[]any{nil, 0, 3}, 3},
+		{[]any{nil, 0, ""}, ""},
+		{[]any{"first", 0}, "first"},
+		{[]any{}, false},
 	}
 
-	got := Find(items, "done", true)
-	if got == nil {
-		t.Fatal("Find by bool: got nil, want item B")
-	}
-	if got.(findItem).Name != "B" {
-		t.Errorf("Find by bool: got %v, want B", got.(findItem).Name)
+	for _, tt := range tests {
+		if got := Or(tt.values...); got != tt.want {
+			t.Errorf("Or(%v) = %#v, want %#v", tt.values, got, tt.want)
+		}
 	}
 }
 
-func TestFind_ByIntField(t *testing.T) {
-	items := []findItem{
-		{Id: 1, Name: "A"},
-		{Id: 2, Name: "B"},
-		{Id: 3, Name: "C"},
+func TestLower(t *testing.T) {
+	if got := Lower("HELLO"); got != "hello" {
+		t.Errorf("Lower(HELLO) = %v, want hello", got)
 	}
+}
 
-	got := Find(items, "id", 2)
-	if got == nil {
-		t.Fatal("Find by int: got nil, want item B")
-	}
-	if got.(findItem).Name != "B" {
-		t.Errorf("Find by int: got %v, want B", got.(findItem).Name)
+func TestUpper(t *testing.T) {
+	if got := Upper("hello"); got != "HELLO" {
+		t.Errorf("Upper(hello) = %v, want HELLO", got)
 	}
 }
 
-func TestFind_NotFound(t *testing.T) {
-	items := []findItem{
-		{Id: 1, Name: "A"},
+func TestTrim(t *testing.T) {
+	if got := Trim("  hello  "); got != "hello" {
+		t.Errorf("Trim(  hello  ) = %v, want hello", got)
 	}
+}
 
-	got := Find(items, "id", 99)
-	if got != nil {
-		t.Errorf("Find not found: got %v, want nil", got)
+func TestContains(t *testing.T) {
+	if !Contains("hello world", "world") {
+		t.Error("Contains(hello world, world) should be true")
+	}
+	if Contains("hello world", "foo") {
+		t.Error("Contains(hello world, foo) should be false")
 	}
 }
 
-func TestFind_EmptySlice(t *testing.T) {
-	var items []findItem
-	got := Find(items, "id", 1)
-	if got != nil {
-		t.Errorf("Find empty: got %v, want nil", got)
+func TestJoin(t *testing.T) {
+	items := []string{"a", "b", "c"}
+	if got := Join(items, ", "); got != "a, b, c" {
+		t.Errorf("Join(%v, ', ') = %v, want 'a, b, c'", items, got)
 	}
 }
 
-func TestFindIndex_Found(t *testing.T) {
-	items := []findItem{
-		{Id: 1, Name: "A", Done: false},
-		{Id: 2, Name: "B", Done: true},
-		{Id: 3, Name: "C", Done: false},
+func TestJoinField(t *testing.T) {
+	type author struct {
+		Name string
+		ID   int
 	}
+	authors := []author{{"Ada", 1}, {"Grace", 2}, {"Linus", 3}}
 
-	got := FindIndex(items, "done", true)
-	if got != 1 {
-		t.Errorf("FindIndex found: got %d, want 1", got)
+	if got := JoinField(authors, "name", ", "); got != "Ada, Grace, Linus" {
+		t.Errorf("JoinField(name) = %q, want %q", got, "Ada, Grace, Linus")
+	}
+	if got := JoinField(authors, "ID", "-"); got != "1-2-3" {
+		t.Errorf("JoinField(ID) = %q, want %q", got, "1-2-3")
+	}
+	if got := JoinField([]*author{{Name: "Ada"}, nil}, "name", "|"); got != "Ada|" {
+		t.Errorf("JoinField(nil element) = %q, want %q", got, "Ada|")
+	}
+	if got := JoinField("not a slice", "name", ","); got != "" {
+		t.Errorf("JoinField(non-slice) = %q, want empty", got)
 	}
 }
 
-func TestFindIndex_NotFound(t *testing.T) {
-	items := []findItem{
-		{Id: 1, Name: "A"},
+func TestTruncate(t *testing.T) {
+	tests := []struct {
+		s      string
+		max    int
+		suffix string
+		want   string
+	}{
+		{"Lorem ipsum dolor", 5, "…", "Lorem…"},
+		{"short", 10, "…", "short"},
+		{"exact", 5, "…", "exact"},
+		{"anything", 0, "…", "…"},
+		{"日本語のテキスト", 3, "…", "日本語…"},
+		{"😀😃😄😁", 2, "...", "😀😃..."},
 	}
 
-	got := FindIndex(items, "id", 99)
-	if got != -1 {
-		t.Errorf("FindIndex not found: got %d, want -1", got)
+	for _, tt := range tests {
+		got := Truncate(tt.s, tt.max, tt.suffix)
+		if got != tt.want {
+			t.Errorf("Truncate(%q, %d, %q) = %q, want %q", tt.s, tt.max, tt.suffix, got, tt.want)
+		}
 	}
 }
 
-func TestComment(t *testing.T) {
-	got := Comment("cond-start:slot_0")
-	want := "<!--bf-cond-start:slot_0-->"
-	if string(got) != want {
-		t.Errorf("Comment(cond-start:slot_0) = %v, want %v", got, want)
+func TestCapitalize(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"hello world", "Hello world"},
+		{"", ""},
+		{"élan", "Élan"},
+		{"ñandú", "Ñandú"},
+		{"Already", "Already"},
+	}
+
+	for _, tt := range tests {
+		got := Capitalize(tt.s)
+		if got != tt.want {
+			t.Errorf("Capitalize(%q) = %q, want %q", tt.s, got, tt.want)
+		}
 	}
 }
 
-func TestTextMarkers(t *testing.T) {
-	gotStart := TextStart("s0")
-	wantStart := "<!--bf:s0-->"
-	if string(gotStart) != wantStart {
-		t.Errorf("TextStart(s0) = %v, want %v", gotStart, wantStart)
+func TestTitle(t *testing.T) {
+	tests := []struct {
+		s    string
+		want string
+	}{
+		{"hello world", "Hello World"},
+		{"the  quick\tbrown fox", "The  Quick\tBrown Fox"},
+		{"école normale", "École Normale"},
+		{"", ""},
 	}
 
-	gotEnd := TextEnd()
-	wantEnd := "<!--/-->"
-	if string(gotEnd) != wantEnd {
-		t.Errorf("TextEnd() = %v, want %v", gotEnd, wantEnd)
+	for _, tt := range tests {
+		got := Title(tt.s)
+		if got != tt.want {
+			t.Errorf("Title(%q) = %q, want %q", tt.s, got, tt.want)
+		}
 	}
 }
 
-func TestFuncMap(t *testing.T) {
-	fm := FuncMap()
-
-	// Check that all expected functions are present
-	expectedFuncs := []string{
-		"bf_add", "bf_sub", "bf_mul", "bf_div", "bf_mod", "bf_neg",
-		"bf_lower", "bf_upper", "bf_trim", "bf_contains", "bf_join",
-		"bf_len", "bf_at", "bf_includes", "bf_first", "bf_last",
-		"bf_every", "bf_some", "bf_filter", "bf_find", "bf_find_index", "bf_sort",
-		"bfComment", "bfTextStart", "bfTextEnd", "bfPortalHTML",
+func TestPadStart(t *testing.T) {
+	tests := []struct {
+		s      any
+		length int
+		pad    string
+		want   string
+	}{
+		{"7", 3, "0", "007"},
+		{7, 3, "0", "007"},
+		{"5", 2, "0", "05"},
+		{"abc", 10, "123", "1231231abc"},
+		{"already", 3, "0", "already"},
+		{"x", 3, "", "x"},
+		{"日本", 4, "・", "・・日本"},
 	}
 
-	for _, name := range expectedFuncs {
-		if _, ok := fm[name]; !ok {
-			t.Errorf("FuncMap missing function: %s", name)
+	for _, tt := range tests {
+		got := PadStart(tt.s, tt.length, tt.pad)
+		if got != tt.want {
+			t.Errorf("PadStart(%v, %d, %q) = %q, want %q", tt.s, tt.length, tt.pad, got, tt.want)
 		}
 	}
 }
 
-// =============================================================================
-// Portal HTML Rendering Tests
-// =============================================================================
-
-func TestPortalHTML_Static(t *testing.T) {
+func TestPadEnd(t *testing.T) {
+	tests := []struct {
+		s      any
+		length int
+		pad    string
+		want   string
+	}{
+		{"abc", 6, "-", "abc---"},
+		{"abc", 6, "xy", "abcxyx"},
+		{42, 4, "0", "4200"},
+		{"日本", 3, "ab", "日本a"},
+	}
+
+	for _, tt := range tests {
+		got := PadEnd(tt.s, tt.length, tt.pad)
+		if got != tt.want {
+			t.Errorf("PadEnd(%v, %d, %q) = %q, want %q", tt.s, tt.length, tt.pad, got, tt.want)
+		}
+	}
+}
+
+func TestFormatNumber(t *testing.T) {
+	tests := []struct {
+		v    any
+		sep  string
+		want string
+	}{
+		{1234567, ",", "1,234,567"},
+		{1234567, "", "1,234,567"},
+		{1000, ".", "1.000"},
+		{999, ",", "999"},
+		{0, ",", "0"},
+		{-1234, ",", "-1,234"},
+		{-999, ",", "-999"},
+		{1234.5678, ",", "1,234.5678"},
+		{-9876543.21, " ", "-9 876 543.21"},
+	}
+
+	for _, tt := range tests {
+		got := FormatNumber(tt.v, tt.sep)
+		if got != tt.want {
+			t.Errorf("FormatNumber(%v, %q) = %q, want %q", tt.v, tt.sep, got, tt.want)
+		}
+	}
+}
+
+func TestCurrency(t *testing.T) {
+	tests := []struct {
+		v      any
+		symbol string
+		places int
+		want   string
+	}{
+		{1234.5, "$", 2, "$1,234.50"},
+		{1234567, "$", 2, "$1,234,567.00"},
+		{0, "$", 2, "$0.00"},
+		{1234.5, "¥", 0, "¥1,235"},
+		{999, "€", 0, "€999"},
+		{-1234.5, "$", 2, "-$1,234.50"},
+		{-0.001, "$", 2, "$0.00"},
+	}
+
+	for _, tt := range tests {
+		got := Currency(tt.v, tt.symbol, tt.places)
+		if got != tt.want {
+			t.Errorf("Currency(%v, %q, %d) = %q, want %q", tt.v, tt.symbol, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestPercent(t *testing.T) {
+	tests := []struct {
+		part, whole any
+		places      int
+		want        string
+	}{
+		{3, 5, 0, "60%"},
+		{1, 4, 0, "25%"},
+		{1, 1, 0, "100%"},
+		{1, 3, 0, "33%"},
+		{2, 3, 1, "66.7%"},
+		{1, 8, 0, "13%"},
+		{1, 8, 2, "12.50%"},
+		{5, 0, 0, "0%"},
+		{5, 0, 2, "0%"},
+	}
+
+	for _, tt := range tests {
+		got := Percent(tt.part, tt.whole, tt.places)
+		if got != tt.want {
+			t.Errorf("Percent(%v, %v, %d) = %q, want %q", tt.part, tt.whole, tt.places, got, tt.want)
+		}
+	}
+}
+
+func TestFormatDateTime(t *testing.T) {
+	tm := time.Date(2026, time.March, 5, 14, 7, 9, 0, time.UTC)
+
+	tests := []struct {
+		name string
+		fn   func(any, string) string
+		t    any
+		lay  string
+		want string
+	}{
+		{"date default", FormatDate, tm, "", "2026-03-05"},
+		{"date layout", FormatDate, tm, "Jan 2, 2006", "Mar 5, 2026"},
+		{"date pointer", FormatDate, &tm, "", "2026-03-05"},
+		{"date RFC3339", FormatDate, "2026-03-05T14:07:09Z", "", "2026-03-05"},
+		{"time default", FormatTime, tm, "", "14:07"},
+		{"time layout", FormatTime, tm, "3:04:05 PM", "2:07:09 PM"},
+		{"time RFC3339", FormatTime, "2026-03-05T14:07:09+09:00", "", "14:07"},
+		{"invalid string", FormatDate, "yesterday", "", ""},
+		{"unsupported type", FormatTime, 42, "", ""},
+		{"nil", FormatDate, nil, "", ""},
+		{"nil pointer", FormatDate, (*time.Time)(nil), "", ""},
+		{"zero time", FormatDate, time.Time{}, "", ""},
+	}
+
+	for _, tt := range tests {
+		if got := tt.fn(tt.t, tt.lay); got != tt.want {
+			t.Errorf("%s: got %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestLen(t *testing.T) {
+	nums := []int{1, 2, 3}
+	var wrapped any = map[string]int{"a": 1, "b": 2}
+
+	tests := []struct {
+		v    any
+		want int
+	}{
+		{[]int{1, 2, 3}, 3},
+		{[]string{}, 0},
+		{"hello", 5},
+		{nil, 0},
+		{map[string]int{"a": 1, "b": 2}, 2},
+		{&nums, 3},
+		{(*[]int)(nil), 0},
+		{&wrapped, 2},
+		{42, 0},
+	}
+
+	for _, tt := range tests {
+		got := Len(tt.v)
+		if got != tt.want {
+			t.Errorf("Len(%v) = %v, want %v", tt.v, got, tt.want)
+		}
+	}
+}
+
+func TestAt(t *testing.T) {
+	items := []string{"a", "b", "c", "d"}
+
+	tests := []struct {
+		index int
+		want  any
+	}{
+		{0, "a"},
+		{1, "b"},
+		{-1, "d"},  // Last element
+		{-2, "c"},  // Second to last
+		{10, nil},  // Out of bounds
+		{-10, nil}, // Out of bounds
+	}
+
+	for _, tt := range tests {
+		got := At(items, tt.index)
+		if got != tt.want {
+			t.Errorf("At(items, %v) = %v, want %v", tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestAt_String(t *testing.T) {
+	tests := []struct {
+		s     string
+		index int
+		want  any
+	}{
+		{"Hello", 0, "H"},
+		{"Hello", -1, "o"},
+		{"日本語", 1, "本"},
+		{"日本語", -1, "語"},
+		{"Hello", 5, ""},
+		{"Hello", -6, ""},
+		{"", 0, ""},
+	}
+
+	for _, tt := range tests {
+		if got := At(tt.s, tt.index); got != tt.want {
+			t.Errorf("At(%q, %d) = %q, want %q", tt.s, tt.index, got, tt.want)
+		}
+	}
+}
+
+func TestGet(t *testing.T) {
+	type theme string
+	settings := map[string]int{"fontSize": 14, "zero": 0}
+	byID := map[int64]string{3: "three"}
+	named := map[theme]bool{"dark": true}
+
+	tests := []struct {
+		name  string
+		items any
+		key   any
+		want  any
+	}{
+		{"map hit", settings, "fontSize", 14},
+		{"map zero value hit", settings, "zero", 0},
+		{"map miss", settings, "missing", nil},
+		{"map wrong key type", settings, 1, nil},
+		{"numeric key coerced", byID, 3, "three"},
+		{"numeric key miss", byID, 4, nil},
+		{"integral float key", byID, 3.0, "three"},
+		{"fractional float key", byID, 3.7, nil},
+		{"int key for uint map", map[uint]string{7: "seven"}, 7, "seven"},
+		{"negative key misses uint map", map[uint8]string{255: "max"}, -1, nil},
+		{"overflowing key", map[int8]string{127: "max"}, 383, nil},
+		{"large int64 key keeps precision", map[int64]string{1<<62 + 1: "odd"}, uint64(1<<62 + 1), "odd"},
+		{"large int64 key no false match", map[int64]string{1 << 62: "even"}, uint64(1<<62 + 1), nil},
+		{"float map, int key", map[float64]string{2: "two"}, 2, "two"},
+		{"string key for numeric map", byID, "3", nil},
+		{"named string key", named, "dark", true},
+		{"slice index", []string{"a", "b"}, -1, "b"},
+		{"slice non-int key", []string{"a"}, "0", nil},
+		{"nil map", map[string]int(nil), "x", nil},
+		{"not a collection", 42, "x", nil},
+		{"nil", nil, "x", nil},
+	}
+
+	for _, tt := range tests {
+		if got := Get(tt.items, tt.key); got != tt.want {
+			t.Errorf("Get(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIncludes(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	if !Includes(items, 3) {
+		t.Error("Includes(items, 3) should be true")
+	}
+	if Includes(items, 10) {
+		t.Error("Includes(items, 10) should be false")
+	}
+}
+
+func TestIncludes_NumericCoercion(t *testing.T) {
+	tests := []struct {
+		name  string
+		items any
+		elem  any
+		want  bool
+	}{
+		{"int slice, int64 elem", []int{1, 2, 3}, int64(2), true},
+		{"int64 slice, int elem", []int64{1, 2, 3}, 3, true},
+		{"uint8 slice, int elem", []uint8{7, 8}, 8, true},
+		{"float slice, int elem", []float64{1.5, 2}, 2, true},
+		{"int slice, float elem", []int{1, 2}, 1.5, false},
+		{"string slice hit", []string{"a", "b"}, "b", true},
+		{"string slice miss", []string{"a", "b"}, "c", false},
+		{"string vs number", []string{"1"}, 1, false},
+	}
+
+	for _, tt := range tests {
+		if got := Includes(tt.items, tt.elem); got != tt.want {
+			t.Errorf("Includes(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestIncludesAnyAll(t *testing.T) {
+	tags := []string{"go", "web", "ssr"}
+	tests := []struct {
+		name       string
+		items      any
+		candidates any
+		wantAny    bool
+		wantAll    bool
+	}{
+		{"full overlap", tags, []string{"ssr", "go"}, true, true},
+		{"partial overlap", tags, []string{"go", "rust"}, true, false},
+		{"no overlap", tags, []string{"rust", "zig"}, false, false},
+		{"empty candidates", tags, []string{}, false, true},
+		{"empty items", []string{}, []string{"go"}, false, false},
+		{"numeric coercion", []int{1, 2, 3}, []int64{2, 3}, true, true},
+		{"candidates not a slice", tags, "go", false, false},
+	}
+
+	for _, tt := range tests {
+		if got := IncludesAny(tt.items, tt.candidates); got != tt.wantAny {
+			t.Errorf("IncludesAny(%s) = %v, want %v", tt.name, got, tt.wantAny)
+		}
+		if got := IncludesAll(tt.items, tt.candidates); got != tt.wantAll {
+			t.Errorf("IncludesAll(%s) = %v, want %v", tt.name, got, tt.wantAll)
+		}
+	}
+}
+
+func TestIndexOf(t *testing.T) {
+	items := []string{"a", "b", "c", "b"}
+
+	if got := IndexOf(items, "b"); got != 1 {
+		t.Errorf("IndexOf(items, b) = %d, want 1", got)
+	}
+	if got := LastIndexOf(items, "b"); got != 3 {
+		t.Errorf("LastIndexOf(items, b) = %d, want 3", got)
+	}
+	if got := IndexOf([]int{10, 20}, 20); got != 1 {
+		t.Errorf("IndexOf([]int, 20) = %d, want 1", got)
+	}
+}
+
+func TestIndexOf_NotFound(t *testing.T) {
+	items := []string{"a", "b"}
+
+	if got := IndexOf(items, "z"); got != -1 {
+		t.Errorf("IndexOf(items, z) = %d, want -1", got)
+	}
+	if got := LastIndexOf(items, "z"); got != -1 {
+		t.Errorf("LastIndexOf(items, z) = %d, want -1", got)
+	}
+	if got := IndexOf(nil, "a"); got != -1 {
+		t.Errorf("IndexOf(nil, a) = %d, want -1", got)
+	}
+}
+
+func TestFirst(t *testing.T) {
+	items := []string{"a", "b", "c"}
+	if got := First(items); got != "a" {
+		t.Errorf("First(items) = %v, want 'a'", got)
+	}
+
+	empty := []string{}
+	if got := First(empty); got != nil {
+		t.Errorf("First(empty) = %v, want nil", got)
+	}
+}
+
+func TestLast(t *testing.T) {
+	items := []string{"a", "b", "c"}
+	if got := Last(items); got != "c" {
+		t.Errorf("Last(items) = %v, want 'c'", got)
+	}
+
+	empty := []string{}
+	if got := Last(empty); got != nil {
+		t.Errorf("Last(empty) = %v, want nil", got)
+	}
+}
+
+func TestReverse_String(t *testing.T) {
+	if got := Reverse("abc"); got != "cba" {
+		t.Errorf("Reverse(abc) = %v, want cba", got)
+	}
+	if got := Reverse("hi😀ñ"); got != "ñ😀ih" {
+		t.Errorf("Reverse(hi😀ñ) = %v, want ñ😀ih", got)
+	}
+}
+
+func TestReverse_Slice(t *testing.T) {
+	items := []int{1, 2, 3}
+	got, ok := Reverse(items).([]any)
+	if !ok {
+		t.Fatalf("Reverse([]int) returned %T, want []any", Reverse(items))
+	}
+	if len(got) != 3 || got[0] != 3 || got[1] != 2 || got[2] != 1 {
+		t.Errorf("Reverse([]int{1, 2, 3}) = %v, want [3 2 1]", got)
+	}
+	if items[0] != 1 {
+		t.Errorf("Reverse mutated original: first = %v, want 1", items[0])
+	}
+}
+
+func TestReverse_NilAndUnsupported(t *testing.T) {
+	var items []int
+	got, ok := Reverse(items).([]any)
+	if !ok || got == nil || len(got) != 0 {
+		t.Errorf("Reverse(nil slice) = %#v, want empty []any", Reverse(items))
+	}
+	if got := Reverse(42); got != 42 {
+		t.Errorf("Reverse(42) = %v, want 42", got)
+	}
+}
+
+func TestSliceRange(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		start, end int
+		want       []any
+	}{
+		{0, 2, []any{1, 2}},
+		{-2, 5, []any{4, 5}},
+		{1, 100, []any{2, 3, 4, 5}},
+		{-100, 1, []any{1}},
+		{3, 1, []any{}},
+		{1, -1, []any{2, 3, 4}},
+	}
+
+	for _, tt := range tests {
+		got, ok := SliceRange(items, tt.start, tt.end).([]any)
+		if !ok {
+			t.Fatalf("SliceRange(items, %d, %d) returned %T, want []any", tt.start, tt.end, got)
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("SliceRange(items, %d, %d) = %v, want %v", tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestSliceRange_String(t *testing.T) {
+	tests := []struct {
+		s          string
+		start, end int
+		want       string
+	}{
+		{"hello", 1, 3, "el"},
+		{"日本語テキスト", 0, 3, "日本語"},
+		{"😀😃😄", -2, 10, "😃😄"},
+	}
+
+	for _, tt := range tests {
+		if got := SliceRange(tt.s, tt.start, tt.end); got != tt.want {
+			t.Errorf("SliceRange(%q, %d, %d) = %v, want %q", tt.s, tt.start, tt.end, got, tt.want)
+		}
+	}
+}
+
+func TestRange(t *testing.T) {
+	tests := []struct {
+		start, end, step int
+		want             []int
+	}{
+		{1, 6, 1, []int{1, 2, 3, 4, 5}},
+		{0, 10, 3, []int{0, 3, 6, 9}},
+		{5, 0, -1, []int{5, 4, 3, 2, 1}},
+		{10, 1, -4, []int{10, 6, 2}},
+		{1, 5, 0, []int{}},
+		{5, 1, 1, []int{}},
+	}
+
+	for _, tt := range tests {
+		got := Range(tt.start, tt.end, tt.step)
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Range(%d, %d, %d) = %v, want %v", tt.start, tt.end, tt.step, got, tt.want)
+		}
+	}
+}
+
+func TestChunk_ExactMultiple(t *testing.T) {
+	got := Chunk([]int{1, 2, 3, 4, 5, 6}, 3)
+	if len(got) != 2 || len(got[0]) != 3 || len(got[1]) != 3 {
+		t.Fatalf("Chunk(6 items, 3) = %v, want 2 chunks of 3", got)
+	}
+	if got[0][0] != 1 || got[1][2] != 6 {
+		t.Errorf("Chunk(6 items, 3) = %v, want [[1 2 3] [4 5 6]]", got)
+	}
+}
+
+func TestChunk_Remainder(t *testing.T) {
+	got := Chunk([]string{"a", "b", "c", "d", "e"}, 2)
+	if len(got) != 3 || len(got[2]) != 1 || got[2][0] != "e" {
+		t.Errorf("Chunk(5 items, 2) = %v, want [[a b] [c d] [e]]", got)
+	}
+}
+
+func TestChunk_ZeroSize(t *testing.T) {
+	got := Chunk([]int{1, 2, 3}, 0)
+	if len(got) != 1 || len(got[0]) != 3 {
+		t.Errorf("Chunk(3 items, 0) = %v, want single chunk", got)
+	}
+
+	empty := Chunk([]int{}, 0)
+	if empty == nil || len(empty) != 0 {
+		t.Errorf("Chunk(empty, 0) = %v, want empty", empty)
+	}
+}
+
+func TestChunk_Template(t *testing.T) {
+	tmpl := template.Must(template.New("grid").Funcs(FuncMap()).Parse(
+		`{{range bf_chunk . 2}}<tr>{{range .}}<td>{{.}}</td>{{end}}</tr>{{end}}`,
+	))
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, []int{1, 2, 3}); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	want := "<tr><td>1</td><td>2</td></tr><tr><td>3</td></tr>"
+	if buf.String() != want {
+		t.Errorf("Chunk template = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestTakeDrop(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5}
+
+	tests := []struct {
+		name     string
+		items    any
+		n        int
+		wantTake []any
+		wantDrop []any
+	}{
+		{"head", items, 2, []any{1, 2}, []any{3, 4, 5}},
+		{"zero", items, 0, []any{}, []any{1, 2, 3, 4, 5}},
+		{"over-length", items, 10, []any{1, 2, 3, 4, 5}, []any{}},
+		{"exact length", items, 5, []any{1, 2, 3, 4, 5}, []any{}},
+		{"negative", items, -2, []any{4, 5}, []any{1, 2, 3}},
+		{"negative over-length", items, -10, []any{1, 2, 3, 4, 5}, []any{}},
+		{"empty", []string{}, 3, []any{}, []any{}},
+		{"not a slice", "abc", 1, nil, nil},
+	}
+
+	for _, tt := range tests {
+		if got := Take(tt.items, tt.n); !reflect.DeepEqual(got, tt.wantTake) {
+			t.Errorf("Take(%s, %d) = %v, want %v", tt.name, tt.n, got, tt.wantTake)
+		}
+		if got := Drop(tt.items, tt.n); !reflect.DeepEqual(got, tt.wantDrop) {
+			t.Errorf("Drop(%s, %d) = %v, want %v", tt.name, tt.n, got, tt.wantDrop)
+		}
+	}
+}
+
+func TestFlatten(t *testing.T) {
+	tests := []struct {
+		name  string
+		items any
+		want  []any
+	}{
+		{"slice of slices", [][]int{{1, 2}, {3}, {}}, []any{1, 2, 3}},
+		{"mixed", []any{1, []int{2, 3}, "four", []string{"five"}}, []any{1, 2, 3, "four", "five"}},
+		{"one level only", []any{[]any{1, []int{2}}}, []any{1, []int{2}}},
+		{"chunk round trip", Chunk([]int{1, 2, 3}, 2), []any{1, 2, 3}},
+		{"empty", [][]int{}, []any{}},
+		{"not a slice", 42, nil},
+	}
+
+	for _, tt := range tests {
+		if got := Flatten(tt.items); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("Flatten(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestPaginate(t *testing.T) {
+	items := []int{1, 2, 3, 4, 5, 6, 7}
+
+	tests := []struct {
+		name      string
+		page      int
+		wantItems []any
+		wantPage  int
+	}{
+		{"first page", 1, []any{1, 2, 3}, 1},
+		{"middle page", 2, []any{4, 5, 6}, 2},
+		{"past last page", 9, []any{7}, 3},
+		{"before first page", 0, []any{1, 2, 3}, 1},
+	}
+
+	for _, tt := range tests {
+		got := Paginate(items, tt.page, 3)
+		if !reflect.DeepEqual(got.Items, tt.wantItems) || got.Page != tt.wantPage {
+			t.Errorf("Paginate %s = items %v page %d, want items %v page %d",
+				tt.name, got.Items, got.Page, tt.wantItems, tt.wantPage)
+		}
+		if got.TotalPages != 3 || got.Total != 7 {
+			t.Errorf("Paginate %s metadata = %d pages / %d total, want 3 / 7", tt.name, got.TotalPages, got.Total)
+		}
+	}
+}
+
+func TestPaginate_Empty(t *testing.T) {
+	got := Paginate([]int{}, 3, 10)
+	want := PageResult{Items: []any{}, Page: 1, TotalPages: 1, Total: 0}
+	if !reflect.DeepEqual(got, want) {
+		t.Errorf("Paginate(empty) = %+v, want %+v", got, want)
+	}
+}
+
+func TestSumAvg(t *testing.T) {
+	tests := []struct {
+		name    string
+		items   any
+		wantSum any
+		wantAvg float64
+	}{
+		{"ints", []int{1, 2, 3, 4}, 10, 2.5},
+		{"floats", []float64{1.5, 2.5, 3.5}, 7.5, 2.5},
+		{"whole floats", []float64{1, 2}, 3.0, 1.5},
+		{"empty", []int{}, 0, 0},
+		{"nil", nil, 0, 0},
+	}
+
+	for _, tt := range tests {
+		if got := Sum(tt.items); got != tt.wantSum {
+			t.Errorf("Sum(%s) = %v (%T), want %v (%T)", tt.name, got, got, tt.wantSum, tt.wantSum)
+		}
+		if got := Avg(tt.items); got != tt.wantAvg {
+			t.Errorf("Avg(%s) = %v, want %v", tt.name, got, tt.wantAvg)
+		}
+	}
+}
+
+// =============================================================================
+// EveryEq / SomeEq Tests
+// =============================================================================
+
+type statusItem struct {
+	Status   string
+	Priority int64
+}
+
+func TestEveryEqSomeEq(t *testing.T) {
+	items := []statusItem{
+		{Status: "active", Priority: 1},
+		{Status: "active", Priority: 2},
+		{Status: "archived", Priority: 2},
+	}
+
+	tests := []struct {
+		name      string
+		items     any
+		field     string
+		value     any
+		wantEvery bool
+		wantSome  bool
+	}{
+		{"string partial", items, "status", "active", false, true},
+		{"string all", items[:2], "status", "active", true, true},
+		{"string none", items, "status", "deleted", false, false},
+		{"int literal vs int64 field", items[1:], "priority", 2, true, true},
+		{"int partial", items, "priority", 1, false, true},
+		{"missing field", items, "owner", "x", false, false},
+		{"pointer items", []*statusItem{{Status: "active"}}, "status", "active", true, true},
+		{"empty", []statusItem{}, "status", "active", true, false},
+	}
+
+	for _, tt := range tests {
+		if got := EveryEq(tt.items, tt.field, tt.value); got != tt.wantEvery {
+			t.Errorf("EveryEq(%s) = %v, want %v", tt.name, got, tt.wantEvery)
+		}
+		if got := SomeEq(tt.items, tt.field, tt.value); got != tt.wantSome {
+			t.Errorf("SomeEq(%s) = %v, want %v", tt.name, got, tt.wantSome)
+		}
+	}
+}
+
+// =============================================================================
+// Find / FindIndex Tests
+// =============================================================================
+
+type findItem struct {
+	Id   int
+	Name string
+	Done bool
+}
+
+func TestFind_ByBooleanField(t *testing.T) {
+	items := []findItem{
+		{Id: 1, Name: "A", Done: false},
+		{Id: 2, Name: "B", Done: true},
+		{Id: 3, Name: "C", Done: false},
+	}
+
+	got := Find(items, "done", true)
+	if got == nil {
+		t.Fatal("Find by bool: got nil, want item B")
+	}
+	if got.(findItem).Name != "B" {
+		t.Errorf("Find by bool: got %v, want B", got.(findItem).Name)
+	}
+}
+
+func TestFind_ByIntField(t *testing.T) {
+	items := []findItem{
+		{Id: 1, Name: "A"},
+		{Id: 2, Name: "B"},
+		{Id: 3, Name: "C"},
+	}
+
+	got := Find(items, "id", 2)
+	if got == nil {
+		t.Fatal("Find by int: got nil, want item B")
+	}
+	if got.(findItem).Name != "B" {
+		t.Errorf("Find by int: got %v, want B", got.(findItem).Name)
+	}
+}
+
+func TestFind_NotFound(t *testing.T) {
+	items := []findItem{
+		{Id: 1, Name: "A"},
+	}
+
+	got := Find(items, "id", 99)
+	if got != nil {
+		t.Errorf("Find not found: got %v, want nil", got)
+	}
+}
+
+func TestFind_EmptySlice(t *testing.T) {
+	var items []findItem
+	got := Find(items, "id", 1)
+	if got != nil {
+		t.Errorf("Find empty: got %v, want nil", got)
+	}
+}
+
+func TestFindIndex_Found(t *testing.T) {
+	items := []findItem{
+		{Id: 1, Name: "A", Done: false},
+		{Id: 2, Name: "B", Done: true},
+		{Id: 3, Name: "C", Done: false},
+	}
+
+	got := FindIndex(items, "done", true)
+	if got != 1 {
+		t.Errorf("FindIndex found: got %d, want 1", got)
+	}
+}
+
+func TestFindIndex_NotFound(t *testing.T) {
+	items := []findItem{
+		{Id: 1, Name: "A"},
+	}
+
+	got := FindIndex(items, "id", 99)
+	if got != -1 {
+		t.Errorf("FindIndex not found: got %d, want -1", got)
+	}
+}
+
+type findAuthor struct {
+	ID   int
+	Name string
+}
+
+type findPost struct {
+	Title  string
+	Author *findAuthor
+}
+
+func TestFind_NestedFieldPath(t *testing.T) {
+	posts := []findPost{
+		{Title: "Intro", Author: &findAuthor{ID: 1, Name: "Ada"}},
+		{Title: "Draft", Author: nil},
+		{Title: "Follow-up", Author: &findAuthor{ID: 2, Name: "Grace"}},
+		{Title: "Reply", Author: &findAuthor{ID: 2, Name: "Grace"}},
+	}
+
+	got := Find(posts, "Author.ID", 2)
+	if p, ok := got.(findPost); !ok || p.Title != "Follow-up" {
+		t.Errorf("Find(Author.ID == 2) = %v, want Follow-up", got)
+	}
+	if got := FindIndex(posts, "author.name", "Grace"); got != 2 {
+		t.Errorf("FindIndex(author.name == Grace) = %d, want 2", got)
+	}
+	if got := Filter(posts, "Author.ID", 2); len(got) != 2 {
+		t.Errorf("Filter(Author.ID == 2) = %v, want 2 items", got)
+	}
+
+	// Nil intermediate pointer and missing segments are no-matches, not panics
+	if got := FindIndex(posts, "Author.ID", 0); got != -1 {
+		t.Errorf("FindIndex(Author.ID == 0) = %d, want -1", got)
+	}
+	if got := Find(posts, "author.missing", 1); got != nil {
+		t.Errorf("Find(author.missing) = %v, want nil", got)
+	}
+	if got := Find(posts, "title.length", 5); got != nil {
+		t.Errorf("Find(title.length) = %v, want nil", got)
+	}
+}
+
+type rangeTask struct {
+	Name     string
+	Priority any
+}
+
+func TestFilterRange(t *testing.T) {
+	tasks := []rangeTask{
+		{"a", 1},
+		{"b", 2},
+		{"c", 3.5},
+		{"d", int64(5)},
+		{"e", 6},
+		{"f", "4"},
+		{"g", nil},
+	}
+	names := func(items []any) []string {
+		var out []string
+		for _, it := range items {
+			out = append(out, it.(rangeTask).Name)
+		}
+		return out
+	}
+
+	tests := []struct {
+		name   string
+		lo, hi any
+		want   []string
+	}{
+		{"inclusive bounds", 2, 5, []string{"b", "c", "d"}},
+		{"float bounds", 1.5, 3.5, []string{"b", "c"}},
+		{"single point", 6, 6, []string{"e"}},
+		{"out of range", 10, 20, nil},
+		{"inverted range", 5, 2, nil},
+		{"non-numeric bound", "2", 5, nil},
+	}
+
+	for _, tt := range tests {
+		if got := names(FilterRange(tasks, "priority", tt.lo, tt.hi)); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("FilterRange(%s) = %v, want %v", tt.name, got, tt.want)
+		}
+	}
+
+	if got := FilterRange(tasks, "missing", 0, 10); got != nil {
+		t.Errorf("FilterRange(missing field) = %v, want nil", got)
+	}
+}
+
+func TestFirstIncomplete(t *testing.T) {
+	tests := []struct {
+		name  string
+		items []findItem
+		want  int
+	}{
+		{"all complete", []findItem{{Done: true}, {Done: true}}, -1},
+		{"first incomplete", []findItem{{Done: false}, {Done: true}}, 0},
+		{"middle incomplete", []findItem{{Done: true}, {Done: false}, {Done: false}}, 1},
+		{"empty", []findItem{}, -1},
+	}
+
+	for _, tt := range tests {
+		if got := FirstIncomplete(tt.items, "done"); got != tt.want {
+			t.Errorf("FirstIncomplete %s = %d, want %d", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestScopeID(t *testing.T) {
+	tests := []struct {
+		name string
+		key  any
+		want string
+	}{
+		{"int key", 42, "TodoItem_42"},
+		{"uint key", uint(7), "TodoItem_7"},
+		{"dash key", "abc-1", "TodoItem_abc-1"},
+		{"underscore", "a_b", "TodoItem_a_x5fb"},
+		{"string with spaces", "buy milk", "TodoItem_buy_x20milk"},
+		{"unsafe characters", `a"b<c>`, "TodoItem_a_x22b_x3cc_x3e"},
+		{"multibyte", "é", "TodoItem__xc3_xa9"},
+		{"slot-like key", "s4", "TodoItem__x734"},
+		{"slot-like multi-digit key", "s12", "TodoItem__x7312"},
+		{"s prefix with letters", "s4a", "TodoItem_s4a"},
+		{"empty", "", "TodoItem_"},
+		{"nil", nil, "TodoItem_"},
+	}
+
+	for _, tt := range tests {
+		if got := ScopeID("TodoItem", tt.key); got != tt.want {
+			t.Errorf("ScopeID(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+
+	// A generated ID is never mistaken for a child slot (_s<N>).
+	for _, key := range []any{"s4", "s0", "s123"} {
+		id := ScopeID("Tab", key)
+		if got := ScopeAttr(&renderTestProps{ScopeID: id}); got != id {
+			t.Errorf("ScopeAttr(ScopeID(%q)) = %q, want root ID %q", key, got, id)
+		}
+	}
+
+	// Keys that differ only in disallowed characters must not collide.
+	for _, pair := range [][2]string{{"a b", "a-b"}, {"é", "ü"}, {"a_x20", "a "}, {"s4", "_x734"}} {
+		if a, b := ScopeID("T", pair[0]), ScopeID("T", pair[1]); a == b {
+			t.Errorf("ScopeID(%q) and ScopeID(%q) collide: %q", pair[0], pair[1], a)
+		}
+	}
+}
+
+func TestRootScopeID(t *testing.T) {
+	got := RootScopeID("TodoApp")
+	if !regexp.MustCompile(`^TodoApp_[a-z0-9]{6}$`).MatchString(got) {
+		t.Errorf("RootScopeID = %q, want TodoApp_ plus 6 lowercase alphanumerics", got)
+	}
+}
+
+func TestScopeAttr(t *testing.T) {
+	tests := []struct {
+		name  string
+		props any
+		want  string
+	}{
+		{"root", &renderTestProps{ScopeID: "Counter_abc123"}, "Counter_abc123"},
+		{"BfIsChild", &renderChildProps{ScopeID: "Item_1", BfIsChild: true}, "~Item_1"},
+		{"single-digit slot", &renderTestProps{ScopeID: "Parent_abc_s4"}, "~Parent_abc_s4"},
+		{"multi-digit slot", &renderTestProps{ScopeID: "Parent_abc_s12"}, "~Parent_abc_s12"},
+		{"_state is not a slot", &renderTestProps{ScopeID: "Foo_state"}, "Foo_state"},
+		{"slot not at end", &renderTestProps{ScopeID: "Parent_s1_abc"}, "Parent_s1_abc"},
+		{"bare _s", &renderTestProps{ScopeID: "Foo_s"}, "Foo_s"},
+	}
+
+	for _, tt := range tests {
+		if got := ScopeAttr(tt.props); got != tt.want {
+			t.Errorf("ScopeAttr(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestComment(t *testing.T) {
+	got := Comment("cond-start:slot_0")
+	want := "<!--bf-cond-start:slot_0-->"
+	if string(got) != want {
+		t.Errorf("Comment(cond-start:slot_0) = %v, want %v", got, want)
+	}
+}
+
+func TestCommentEnd(t *testing.T) {
+	got := CommentEnd("cond:slot_0")
+	want := "<!--/bf-cond:slot_0-->"
+	if string(got) != want {
+		t.Errorf("CommentEnd(cond:slot_0) = %v, want %v", got, want)
+	}
+}
+
+func TestTextMarkers(t *testing.T) {
+	gotStart := TextStart("s0")
+	wantStart := "<!--bf:s0-->"
+	if string(gotStart) != wantStart {
+		t.Errorf("TextStart(s0) = %v, want %v", gotStart, wantStart)
+	}
+
+	gotEnd := TextEnd()
+	wantEnd := "<!--/-->"
+	if string(gotEnd) != wantEnd {
+		t.Errorf("TextEnd() = %v, want %v", gotEnd, wantEnd)
+	}
+
+	gotEndID := TextEnd("s0")
+	wantEndID := "<!--/bf:s0-->"
+	if string(gotEndID) != wantEndID {
+		t.Errorf("TextEnd(s0) = %v, want %v", gotEndID, wantEndID)
+	}
+}
+
+func TestTextMarkers_InTemplate(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "T"}}{{bfTextStart "s0"}}{{.}}{{bfTextEnd}}|{{bfTextStart "s1"}}{{.}}{{bfTextEnd "s1"}}{{end}}`)
+
+	var buf strings.Builder
+	if err := tmpl.ExecuteTemplate(&buf, "T", "Hi"); err != nil {
+		t.Fatal(err)
+	}
+	if want := "<!--bf:s0-->Hi<!--/-->|<!--bf:s1-->Hi<!--/bf:s1-->"; buf.String() != want {
+		t.Errorf("template output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestListMarkers(t *testing.T) {
+	if got, want := ListStart("s3"), template.HTML("<!--bf-list:s3-->"); got != want {
+		t.Errorf("ListStart(s3) = %v, want %v", got, want)
+	}
+	if got, want := ListEnd("s3"), template.HTML("<!--/bf-list:s3-->"); got != want {
+		t.Errorf("ListEnd(s3) = %v, want %v", got, want)
+	}
+
+	tmpl := mustParseTemplate(t, `{{define "L"}}{{bfListStart "s3"}}{{range .}}<li {{bfKey .}}>{{.}}</li>{{end}}{{bfListEnd "s3"}}{{end}}`)
+	var buf strings.Builder
+	if err := tmpl.ExecuteTemplate(&buf, "L", []int{1, 2}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<!--bf-list:s3--><li bf-key="1">1</li><li bf-key="2">2</li><!--/bf-list:s3-->`; buf.String() != want {
+		t.Errorf("template output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestKey(t *testing.T) {
+	tests := []struct {
+		v    any
+		want template.HTMLAttr
+	}{
+		{42, `bf-key="42"`},
+		{uint(5), `bf-key="5"`},
+		{int32(-3), `bf-key="-3"`},
+		{uint64(18446744073709551615), `bf-key="18446744073709551615"`},
+		{2.5, `bf-key="2.5"`},
+		{"todo-1", `bf-key="todo-1"`},
+		{`a"b`, `bf-key="a&#34;b"`},
+		{"", ""},
+		{nil, ""},
+	}
+
+	for _, tt := range tests {
+		if got := Key(tt.v); got != tt.want {
+			t.Errorf("Key(%#v) = %q, want %q", tt.v, got, tt.want)
+		}
+	}
+
+	tmpl := template.Must(template.New("t").Funcs(FuncMap()).Parse(`{{range .}}<li {{bfKey .}}>{{.}}</li>{{end}}`))
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, []int{3, 1}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<li bf-key="3">3</li><li bf-key="1">1</li>`; buf.String() != want {
+		t.Errorf("template output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestFocusTarget(t *testing.T) {
+	got := FocusTarget(`#email[name="x"]`)
+	want := template.HTMLAttr(`data-bf-focus="#email[name=&#34;x&#34;]"`)
+	if got != want {
+		t.Errorf("FocusTarget = %q, want %q", got, want)
+	}
+
+	if got := FocusTarget(""); got != "" {
+		t.Errorf("FocusTarget(\"\") = %q, want empty", got)
+	}
+}
+
+func TestDataJSON_Object(t *testing.T) {
+	got := DataJSON("chart", map[string]any{"label": "Bob's <b>data</b>", "value": 3})
+	want := template.HTMLAttr(`data-chart='{"label":"Bob&#39;s \u003cb\u003edata\u003c/b\u003e","value":3}'`)
+	if got != want {
+		t.Errorf("DataJSON object = %q, want %q", got, want)
+	}
+}
+
+func TestDataJSON_Array(t *testing.T) {
+	got := DataJSON("series", []int{1, 2, 3})
+	want := template.HTMLAttr(`data-series='[1,2,3]'`)
+	if got != want {
+		t.Errorf("DataJSON array = %q, want %q", got, want)
+	}
+}
+
+func TestDataJSON_MarshalError(t *testing.T) {
+	if got := DataJSON("bad", make(chan int)); got != "" {
+		t.Errorf("DataJSON with unmarshalable value = %q, want empty", got)
+	}
+}
+
+func TestDataJSON_InvalidName(t *testing.T) {
+	for _, name := range []string{"", "a b", `x onclick=alert(1) y`, "a'b", "a=b", "a>b"} {
+		if got := DataJSON(name, 1); got != "" {
+			t.Errorf("DataJSON(%q) = %q, want empty", name, got)
+		}
+	}
+	if got := DataJSON("chart_2-x", 1); got != "data-chart_2-x='1'" {
+		t.Errorf("DataJSON valid name = %q, want data-chart_2-x='1'", got)
+	}
+}
+
+func TestJSONString(t *testing.T) {
+	got := JSONString(map[string]any{"html": "<b>a & b</b>", "n": 1})
+	want := `{"html":"\u003cb\u003ea \u0026 b\u003c/b\u003e","n":1}`
+	if got != want {
+		t.Errorf("JSONString = %q, want %q", got, want)
+	}
+	if got := JSONString(make(chan int)); got != "" {
+		t.Errorf("JSONString with unmarshalable value = %q, want empty", got)
+	}
+
+	// Escaped exactly once when used as an attribute value.
+	tmpl := template.Must(template.New("t").Funcs(FuncMap()).Parse(`<div data-config="{{bf_json .}}"></div>`))
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, map[string]string{"q": `"<&>"`}); err != nil {
+		t.Fatal(err)
+	}
+	wantHTML := `<div data-config="{&#34;q&#34;:&#34;\&#34;\u003c\u0026\u003e\&#34;&#34;}"></div>`
+	if buf.String() != wantHTML {
+		t.Errorf("template output = %q, want %q", buf.String(), wantHTML)
+	}
+}
+
+func TestJSONAttr(t *testing.T) {
+	got := JSONAttr(map[string]any{"html": "<b>a & b</b>"})
+	want := `{&#34;html&#34;:&#34;\u003cb\u003ea \u0026 b\u003c/b\u003e&#34;}`
+	if got != want {
+		t.Errorf("JSONAttr = %q, want %q", got, want)
+	}
+	if got := JSONAttr(make(chan int)); got != "" {
+		t.Errorf("JSONAttr with unmarshalable value = %q, want empty", got)
+	}
+}
+
+func TestSafeHTML(t *testing.T) {
+	tmpl := template.Must(template.New("t").Funcs(FuncMap()).Parse(`<article>{{bf_safe_html .}}</article><p>{{.}}</p>`))
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, "<em>hi</em>"); err != nil {
+		t.Fatal(err)
+	}
+	want := `<article><em>hi</em></article><p>&lt;em&gt;hi&lt;/em&gt;</p>`
+	if buf.String() != want {
+		t.Errorf("template output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestNL2BR(t *testing.T) {
+	tests := []struct {
+		in   string
+		want template.HTML
+	}{
+		{"one line", "one line"},
+		{"a\nb", "a<br>b"},
+		{"a\r\nb\rc", "a<br>b<br>c"},
+		{"a\n\nb", "a<br><br>b"},
+		{"<script>alert(1)</script>\nok & done", "&lt;script&gt;alert(1)&lt;/script&gt;<br>ok &amp; done"},
+	}
+
+	for _, tt := range tests {
+		if got := NL2BR(tt.in); got != tt.want {
+			t.Errorf("NL2BR(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
+
+func TestClass(t *testing.T) {
+	tests := []struct {
+		name string
+		args []any
+		want string
+	}{
+		{"static", []any{"btn", "btn-primary"}, "btn btn-primary"},
+		{"pair true", []any{"btn", "active", true}, "btn active"},
+		{"pair false", []any{"btn", "active", false, "lg"}, "btn lg"},
+		{"map", []any{"btn", map[string]bool{"z-on": true, "a-on": true, "off": false}}, "btn a-on z-on"},
+		{"empty and spaces", []any{"", "  btn  ", "", "x  y"}, "btn x y"},
+		{"slice", []any{[]string{"a", "", "b"}, "c"}, "a b c"},
+		{"all false", []any{"a", false, map[string]bool{"b": false}}, ""},
+		{"ignored types", []any{nil, 42, "ok"}, "ok"},
+	}
+
+	for _, tt := range tests {
+		if got := Class(tt.args...); got != tt.want {
+			t.Errorf("Class(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestAttr(t *testing.T) {
+	if got, want := Attr("disabled", true), template.HTMLAttr(`disabled="disabled"`); got != want {
+		t.Errorf("Attr(true) = %q, want %q", got, want)
+	}
+	if got := Attr("disabled", false); got != "" {
+		t.Errorf("Attr(false) = %q, want empty", got)
+	}
+
+	if got, want := AttrVal("aria-label", `Say "hi"`, true), template.HTMLAttr(`aria-label="Say &#34;hi&#34;"`); got != want {
+		t.Errorf("AttrVal(true) = %q, want %q", got, want)
+	}
+	if got, want := AttrVal("tabindex", -1, true), template.HTMLAttr(`tabindex="-1"`); got != want {
+		t.Errorf("AttrVal(int) = %q, want %q", got, want)
+	}
+	if got := AttrVal("aria-label", "x", false); got != "" {
+		t.Errorf("AttrVal(false) = %q, want empty", got)
+	}
+}
+
+func TestAttr_RejectsInvalidName(t *testing.T) {
+	for _, name := range []string{"", "x onmouseover=alert(1) y", `a"b`, "a=b", "a>b", "a b"} {
+		if got := Attr(name, true); got != "" {
+			t.Errorf("Attr(%q) = %q, want empty", name, got)
+		}
+		if got := AttrVal(name, "v", true); got != "" {
+			t.Errorf("AttrVal(%q) = %q, want empty", name, got)
+		}
+	}
+}
+
+func TestAttr_InTemplate(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "T"}}<input {{bf_attr "checked" .On}} {{bf_attr_val "value" .V .On}}>{{end}}`)
+
+	var buf strings.Builder
+	if err := tmpl.ExecuteTemplate(&buf, "T", map[string]any{"On": true, "V": "a&b"}); err != nil {
+		t.Fatal(err)
+	}
+	if want := `<input checked="checked" value="a&amp;b">`; buf.String() != want {
+		t.Errorf("template output = %q, want %q", buf.String(), want)
+	}
+}
+
+func TestStyle(t *testing.T) {
+	tests := []struct {
+		name string
+		m    any
+		want template.HTMLAttr
+	}{
+		{"sorted", map[string]string{"top": "10px", "left": "4px", "position": "absolute"}, `style="left:4px;position:absolute;top:10px"`},
+		{"numeric", map[string]any{"z-index": 50, "opacity": 0.5}, `style="opacity:0.5;z-index:50"`},
+		{"skip empty", map[string]string{"color": "", "width": "1px"}, `style="width:1px"`},
+		{"escaped", map[string]string{"font-family": `"Inter"`}, `style="font-family:&#34;Inter&#34;"`},
+		{"all numeric kinds", map[string]any{"a": uint(1), "b": int32(-2), "c": float32(1.5), "d": int64(3)}, `style="a:1;b:-2;c:1.5;d:3"`},
+		{"skip nil", map[string]any{"color": nil, "width": "1px"}, `style="width:1px"`},
+		{"custom and vendor properties", map[string]string{"--accent": "red", "-webkit-line-clamp": "2"}, `style="--accent:red;-webkit-line-clamp:2"`},
+		{"skip invalid property", map[string]string{"color:red;background": "x", "a b": "y", "": "z", "top": "0"}, `style="top:0"`},
+		{"empty map", map[string]string{}, ""},
+		{"nil", nil, ""},
+		{"not a map", "color:red", ""},
+	}
+
+	for _, tt := range tests {
+		if got := Style(tt.m); got != tt.want {
+			t.Errorf("Style(%s) = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestMotion(t *testing.T) {
+	tests := []struct {
+		extra map[string]interface{}
+		want  string
+	}{
+		{map[string]interface{}{MotionExtraKey: "reduce"}, "reduce"},
+		{map[string]interface{}{MotionExtraKey: "no-preference"}, "no-preference"},
+		{map[string]interface{}{}, "no-preference"},
+		{nil, "no-preference"},
+	}
+
+	for _, tt := range tests {
+		if got := Motion(tt.extra); got != tt.want {
+			t.Errorf("Motion(%v) = %q, want %q", tt.extra, got, tt.want)
+		}
+	}
+}
+
+func TestMotionHint(t *testing.T) {
+	req := httptest.NewRequest("GET", "/", nil)
+	if got := MotionHint(req); got != "no-preference" {
+		t.Errorf("MotionHint without header = %q, want no-preference", got)
+	}
+
+	req.Header.Set("Sec-CH-Prefers-Reduced-Motion", "reduce")
+	if got := MotionHint(req); got != "reduce" {
+		t.Errorf("MotionHint with reduce header = %q, want reduce", got)
+	}
+}
+
+func TestBfPropsAttr_OmitsInternalFields(t *testing.T) {
+	props := &struct {
+		ScopeID   string
+		Count     int `json:"count"`
+		Scripts   *ScriptCollector
+		Portals   *PortalCollector `json:"portals"`
+		BfIsRoot  bool
+		BfIsChild bool
+	}{
+		ScopeID:  "Counter_1",
+		Count:    3,
+		Scripts:  NewScriptCollector(),
+		Portals:  NewPortalCollector(),
+		BfIsRoot: true,
+	}
+
+	got := BfPropsAttr(props)
+	want := template.HTMLAttr(`bf-p="{&#34;ScopeID&#34;:&#34;Counter_1&#34;,&#34;count&#34;:3}"`)
+	if got != want {
+		t.Errorf("BfPropsAttr = %q, want %q", got, want)
+	}
+	if contains(string(got), "Scripts") {
+		t.Errorf("BfPropsAttr should not contain Scripts key, got %q", got)
+	}
+}
+
+func TestBfPropsAttr_OmitsInternalFieldsOfChildren(t *testing.T) {
+	type item struct {
+		ScopeID   string `json:"scopeID"`
+		Title     string `json:"title"`
+		Scripts   *ScriptCollector
+		Portals   *PortalCollector
+		BfIsRoot  bool
+		BfIsChild bool
+	}
+	tmpl := mustParseTemplate(t, `{{define "List"}}<ul bf-s="{{bfScopeAttr .}}" {{bfPropsAttr .}}></ul>{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+
+	got, err := r.RenderE(RenderOptions{ComponentName: "List", Props: &struct {
+		ScopeID  string `json:"scopeID"`
+		Items    []item `json:"items"`
+		Featured *item  `json:"featured"`
+		Scripts  *ScriptCollector
+		BfIsRoot bool
+	}{
+		ScopeID:  "List_1",
+		Items:    []item{{ScopeID: "Item_1", Title: "a"}},
+		Featured: &item{ScopeID: "Item_2", Title: "b"},
+	}})
+	if err != nil {
+		t.Fatalf("RenderE error: %v", err)
+	}
+	want := `bf-p="{&#34;scopeID&#34;:&#34;List_1&#34;,&#34;items&#34;:[{&#34;scopeID&#34;:&#34;Item_1&#34;,&#34;title&#34;:&#34;a&#34;}],` +
+		`&#34;featured&#34;:{&#34;scopeID&#34;:&#34;Item_2&#34;,&#34;title&#34;:&#34;b&#34;}}"`
+	if !contains(got, want) {
+		t.Errorf("RenderE = %q, want bf-p %q", got, want)
+	}
+	for _, key := range internalPropsFields {
+		if contains(got, "&#34;"+key+"&#34;") {
+			t.Errorf("bf-p should not contain %s anywhere, got %q", key, got)
+		}
+	}
+}
+
+func TestBfPropsAttr_OmitsServerFields(t *testing.T) {
+	props := &struct {
+		ScopeID  string
+		Name     string `json:"name"`
+		Email    string `bf:"server"`
+		UserID   int    `json:"userId" bf:"server"`
+		BfIsRoot bool
+	}{
+		ScopeID:  "Profile_1",
+		Name:     "Ada",
+		Email:    "ada@example.com",
+		UserID:   42,
+		BfIsRoot: true,
+	}
+
+	got := BfPropsAttr(props)
+	want := template.HTMLAttr(`bf-p="{&#34;ScopeID&#34;:&#34;Profile_1&#34;,&#34;name&#34;:&#34;Ada&#34;}"`)
+	if got != want {
+		t.Errorf("BfPropsAttr = %q, want %q", got, want)
+	}
+	if contains(string(got), "ada@example.com") {
+		t.Errorf("BfPropsAttr should not leak the Email field, got %q", got)
+	}
+}
+
+func TestMarshalPropsStable_OmitsNestedServerFields(t *testing.T) {
+	type Child struct {
+		Name   string `json:"name"`
+		Secret string `bf:"server"`
+	}
+	props := &struct {
+		ScopeID string
+		Kids    []Child          `json:"kids"`
+		ByID    map[string]Child `json:"byId"`
+		Best    *Child           `json:"best"`
+		Any     any              `json:"any"`
+	}{
+		ScopeID: "Root_1",
+		Kids:    []Child{{Name: "a", Secret: "pw"}},
+		ByID:    map[string]Child{"b": {Name: "b", Secret: "pw"}},
+		Best:    &Child{Name: "c", Secret: "pw"},
+		Any:     Child{Name: "d", Secret: "pw"},
+	}
+
+	got, err := MarshalPropsStable(props)
+	if err != nil {
+		t.Fatalf("MarshalPropsStable error: %v", err)
+	}
+	want := `{"ScopeID":"Root_1","kids":[{"name":"a"}],"byId":{"b":{"name":"b"}},"best":{"name":"c"},"any":{"name":"d"}}`
+	if string(got) != want {
+		t.Errorf("MarshalPropsStable = %s, want %s", got, want)
+	}
+}
+
+func TestMarshalPropsStable_OmitsEmbeddedServerFields(t *testing.T) {
+	type Base struct {
+		ScopeID string
+		Email   string `bf:"server"`
+	}
+	props := &struct {
+		Base
+		Name string `json:"name"`
+	}{
+		Base: Base{ScopeID: "Profile_1", Email: "ada@example.com"},
+		Name: "Ada",
+	}
+
+	got, err := MarshalPropsStable(props)
+	if err != nil {
+		t.Fatalf("MarshalPropsStable error: %v", err)
+	}
+	if want := `{"ScopeID":"Profile_1","name":"Ada"}`; string(got) != want {
+		t.Errorf("MarshalPropsStable = %s, want %s", got, want)
+	}
+}
+
+func TestMarshalPropsStable_KeepsCustomMarshalers(t *testing.T) {
+	props := &struct {
+		ScopeID string
+		At      time.Time         `json:"at"`
+		Raw     json.RawMessage   `json:"raw"`
+		Bytes   []byte            `json:"bytes"`
+		Counts  map[int]time.Time `json:"counts"`
+	}{
+		ScopeID: "Event_1",
+		At:      time.Date(2024, 1, 2, 3, 4, 5, 0, time.UTC),
+		Raw:     json.RawMessage(`{"Secret":1}`),
+		Bytes:   []byte("hi"),
+		Counts:  map[int]time.Time{1: time.Date(2024, 1, 2, 0, 0, 0, 0, time.UTC)},
+	}
+
+	got, err := MarshalPropsStable(props)
+	if err != nil {
+		t.Fatalf("MarshalPropsStable error: %v", err)
+	}
+	want := `{"ScopeID":"Event_1","at":"2024-01-02T03:04:05Z","raw":{"Secret":1},"bytes":"aGk=","counts":{"1":"2024-01-02T00:00:00Z"}}`
+	if string(got) != want {
+		t.Errorf("MarshalPropsStable = %s, want %s", got, want)
+	}
+}
+
+func TestBfPropsAttr_DebugProps(t *testing.T) {
+	props := &struct {
+		ScopeID  string
+		Count    int `json:"count"`
+		BfIsRoot bool
+	}{ScopeID: "Counter_1", Count: 3, BfIsRoot: true}
+
+	DebugProps = true
+	defer func() { DebugProps = false }()
+
+	got := BfPropsAttr(props)
+	want := template.HTMLAttr("bf-p=\"{\n  &#34;ScopeID&#34;: &#34;Counter_1&#34;,\n  &#34;count&#34;: 3\n}\"")
+	if got != want {
+		t.Errorf("BfPropsAttr (DebugProps) = %q, want %q", got, want)
+	}
+
+	DebugProps = false
+	got = BfPropsAttr(props)
+	want = template.HTMLAttr(`bf-p="{&#34;ScopeID&#34;:&#34;Counter_1&#34;,&#34;count&#34;:3}"`)
+	if got != want {
+		t.Errorf("BfPropsAttr = %q, want %q", got, want)
+	}
+}
+
+func TestMarshalPropsStable_MapKeyOrder(t *testing.T) {
+	props := &struct {
+		ScopeID string
+		Counts  map[string]int            `json:"counts"`
+		Nested  map[string]map[string]int `json:"nested"`
+	}{
+		ScopeID: "Stats_1",
+		Counts:  map[string]int{"zeta": 1, "alpha": 2, "mid": 3, "beta": 4},
+		Nested:  map[string]map[string]int{"b": {"y": 1, "x": 2}, "a": {"d": 3, "c": 4}},
+	}
+
+	want := `{"ScopeID":"Stats_1","counts":{"alpha":2,"beta":4,"mid":3,"zeta":1},"nested":{"a":{"c":4,"d":3},"b":{"x":2,"y":1}}}`
+	for i := 0; i < 20; i++ {
+		got, err := MarshalPropsStable(props)
+		if err != nil {
+			t.Fatalf("MarshalPropsStable error: %v", err)
+		}
+		if string(got) != want {
+			t.Fatalf("MarshalPropsStable call %d = %s, want %s", i, got, want)
+		}
+	}
+}
+
+func TestScopeComment_OmitsInternalFields(t *testing.T) {
+	props := &struct {
+		ScopeID  string
+		Label    string `json:"label"`
+		Scripts  *ScriptCollector
+		BfIsRoot bool
+	}{ScopeID: "Frag_1", Label: "hi", BfIsRoot: true}
+
+	got := ScopeComment(props)
+	want := template.HTML(`<!--bf-scope:Frag_1|{"ScopeID":"Frag_1","label":"hi"}-->`)
+	if got != want {
+		t.Errorf("ScopeComment = %q, want %q", got, want)
+	}
+}
+
+func TestFuncMap(t *testing.T) {
+	fm := FuncMap()
+
+	// Check that all expected functions are present
+	expectedFuncs := []string{
+		"bf_add", "bf_sub", "bf_mul", "bf_div", "bf_mod", "bf_neg",
+		"bf_lower", "bf_upper", "bf_trim", "bf_contains", "bf_join",
+		"bf_len", "bf_at", "bf_includes", "bf_first", "bf_last",
+		"bf_every", "bf_some", "bf_filter", "bf_find", "bf_find_index", "bf_sort",
+		"bfComment", "bfCommentEnd", "bfTextStart", "bfTextEnd", "bfPortalHTML", "bfKey",
+		"bfListStart", "bfListEnd",
+	}
+
+	for _, name := range expectedFuncs {
+		if _, ok := fm[name]; !ok {
+			t.Errorf("FuncMap missing function: %s", name)
+		}
+	}
+}
+
+func TestFuncMapNamespaced(t *testing.T) {
+	fm := FuncMapNamespaced("myns")
+	for _, name := range []string{"myns_add", "myns_join", "myns_filter", "bfComment", "bfScopeAttr"} {
+		if _, ok := fm[name]; !ok {
+			t.Errorf("FuncMapNamespaced missing function: %s", name)
+		}
+	}
+	for _, name := range []string{"bf_add", "bf_join"} {
+		if _, ok := fm[name]; ok {
+			t.Errorf("FuncMapNamespaced should not contain %s", name)
+		}
+	}
+	if len(fm) != len(FuncMap()) {
+		t.Errorf("FuncMapNamespaced has %d functions, want %d", len(fm), len(FuncMap()))
+	}
+
+	tmpl := template.Must(template.New("t").Funcs(FuncMap()).Funcs(fm).Parse(`{{myns_add 2 3}} {{bf_add 2 3}} {{myns_add 1.5 2}}`))
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, nil); err != nil {
+		t.Fatal(err)
+	}
+	if want := "5 5 3.5"; buf.String() != want {
+		t.Errorf("template output = %q, want %q", buf.String(), want)
+	}
+}
+
+// =============================================================================
+// Portal HTML Rendering Tests
+// =============================================================================
+
+func TestPortalHTML_Static(t *testing.T) {
 	result := PortalHTML(nil, "<div>Hello</div>")
 	expected := template.HTML("<div>Hello</div>")
 	if result != expected {
@@ -364,264 +2085,1429 @@ func TestPortalHTML_Static(t *testing.T) {
 	}
 }
 
-func TestPortalHTML_Dynamic(t *testing.T) {
-	data := struct {
-		Name string
-	}{Name: "World"}
-
-	result := PortalHTML(data, "<div>Hello {{.Name}}</div>")
-	expected := template.HTML("<div>Hello World</div>")
-	if result != expected {
-		t.Errorf("PortalHTML dynamic = %q, want %q", result, expected)
+func TestPortalHTML_Dynamic(t *testing.T) {
+	data := struct {
+		Name string
+	}{Name: "World"}
+
+	result := PortalHTML(data, "<div>Hello {{.Name}}</div>")
+	expected := template.HTML("<div>Hello World</div>")
+	if result != expected {
+		t.Errorf("PortalHTML dynamic = %q, want %q", result, expected)
+	}
+}
+
+func TestPortalHTML_Conditional(t *testing.T) {
+	data := struct {
+		Open bool
+	}{Open: true}
+
+	result := PortalHTML(data, `<div data-state="{{if .Open}}open{{else}}closed{{end}}"></div>`)
+	expected := template.HTML(`<div data-state="open"></div>`)
+	if result != expected {
+		t.Errorf("PortalHTML conditional = %q, want %q", result, expected)
+	}
+
+	// Test with Open = false
+	data.Open = false
+	result = PortalHTML(data, `<div data-state="{{if .Open}}open{{else}}closed{{end}}"></div>`)
+	expected = template.HTML(`<div data-state="closed"></div>`)
+	if result != expected {
+		t.Errorf("PortalHTML conditional (false) = %q, want %q", result, expected)
+	}
+}
+
+func TestPortalHTML_InvalidTemplate(t *testing.T) {
+	result := PortalHTML(nil, "{{.Unclosed")
+	// Should return error comment instead of panicking
+	if !contains(string(result), "bfPortalHTML error") {
+		t.Errorf("PortalHTML invalid template should return error comment, got %q", result)
+	}
+}
+
+func TestPortalHTML_CachedTemplateFreshData(t *testing.T) {
+	const tmplStr = `<li>{{.Name}}</li>`
+	for _, name := range []string{"a", "b", "c"} {
+		got := PortalHTML(struct{ Name string }{name}, tmplStr)
+		if want := template.HTML("<li>" + name + "</li>"); got != want {
+			t.Errorf("PortalHTML = %q, want %q", got, want)
+		}
+	}
+	if _, ok := portalTemplates.Load(tmplStr); !ok {
+		t.Error("parsed template should be cached")
+	}
+
+	// Invalid templates keep returning the error comment
+	for i := 0; i < 2; i++ {
+		if got := PortalHTML(nil, "{{.Unclosed"); !contains(string(got), "bfPortalHTML error") {
+			t.Errorf("PortalHTML invalid template (call %d) = %q", i, got)
+		}
+	}
+}
+
+func TestPortalHTMLFuncs(t *testing.T) {
+	extra := template.FuncMap{"shout": func(s string) string { return strings.ToUpper(s) + "!" }}
+
+	got := PortalHTMLFuncs(struct{ Msg string }{"saved"}, `<div role="status">{{shout .Msg}}</div>`, extra)
+	if want := template.HTML(`<div role="status">SAVED!</div>`); got != want {
+		t.Errorf("PortalHTMLFuncs = %q, want %q", got, want)
+	}
+
+	// Base helpers remain available alongside extras
+	got = PortalHTMLFuncs(nil, `{{bf_upper "a"}}{{shout "b"}}`, extra)
+	if got != "AB!" {
+		t.Errorf("PortalHTMLFuncs with base helper = %q, want AB!", got)
+	}
+
+	// Without the extra FuncMap the function is unknown: error comment fallback
+	if got := PortalHTML(nil, `{{shout "x"}}`); !contains(string(got), "bfPortalHTML error") {
+		t.Errorf("PortalHTML with unknown function = %q, want error comment", got)
+	}
+}
+
+func BenchmarkPortalHTML(b *testing.B) {
+	const tmplStr = `<div data-state="{{if .Open}}open{{else}}closed{{end}}">{{.Name}}</div>`
+	data := struct {
+		Open bool
+		Name string
+	}{true, "dialog"}
+
+	b.Run("cached", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			PortalHTML(data, tmplStr)
+		}
+	})
+	b.Run("parse each call", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			t, _ := template.New("portal").Funcs(FuncMap()).Parse(tmplStr)
+			t.Execute(io.Discard, data)
+		}
+	})
+}
+
+// =============================================================================
+// Portal Collection Tests
+// =============================================================================
+
+func TestNewPortalCollector(t *testing.T) {
+	pc := NewPortalCollector()
+	if pc == nil {
+		t.Error("NewPortalCollector() returned nil")
+	}
+	if len(pc.portals) != 0 {
+		t.Errorf("NewPortalCollector() should have empty portals, got %d", len(pc.portals))
+	}
+	if pc.counter != 0 {
+		t.Errorf("NewPortalCollector() counter should be 0, got %d", pc.counter)
+	}
+}
+
+func TestPortalCollector_Add(t *testing.T) {
+	pc := NewPortalCollector()
+
+	// Add first portal
+	result := pc.Add("scope-1", "<div>Content 1</div>")
+	if result != "" {
+		t.Errorf("Add() should return empty string, got %q", result)
+	}
+	if len(pc.portals) != 1 {
+		t.Errorf("After first Add(), portals count should be 1, got %d", len(pc.portals))
+	}
+	if pc.portals[0].ID != "bf-portal-1" {
+		t.Errorf("First portal ID should be 'bf-portal-1', got %q", pc.portals[0].ID)
+	}
+	if pc.portals[0].OwnerID != "scope-1" {
+		t.Errorf("First portal OwnerID should be 'scope-1', got %q", pc.portals[0].OwnerID)
+	}
+
+	// Add second portal
+	pc.Add("scope-2", "<div>Content 2</div>")
+	if len(pc.portals) != 2 {
+		t.Errorf("After second Add(), portals count should be 2, got %d", len(pc.portals))
+	}
+	if pc.portals[1].ID != "bf-portal-2" {
+		t.Errorf("Second portal ID should be 'bf-portal-2', got %q", pc.portals[1].ID)
+	}
+}
+
+func TestPortalCollector_Render_Empty(t *testing.T) {
+	pc := NewPortalCollector()
+	result := pc.Render()
+	if result != "" {
+		t.Errorf("Render() on empty collector should return empty string, got %q", result)
+	}
+}
+
+func TestPortalCollector_Render_Nil(t *testing.T) {
+	var pc *PortalCollector
+	result := pc.Render()
+	if result != "" {
+		t.Errorf("Render() on nil collector should return empty string, got %q", result)
+	}
+}
+
+func TestPortalCollector_Render_Single(t *testing.T) {
+	pc := NewPortalCollector()
+	pc.Add("scope-abc", "<div>Portal Content</div>")
+
+	result := string(pc.Render())
+	expected := `<div bf-pi="bf-portal-1" bf-po="scope-abc"><div>Portal Content</div></div>` + "\n"
+	if result != expected {
+		t.Errorf("Render() = %q, want %q", result, expected)
+	}
+}
+
+func TestPortalCollector_Render_Multiple(t *testing.T) {
+	pc := NewPortalCollector()
+	pc.Add("scope-1", "<div>Content 1</div>")
+	pc.Add("scope-2", "<span>Content 2</span>")
+
+	result := string(pc.Render())
+
+	// Check that both portals are rendered
+	if !contains(result, `bf-pi="bf-portal-1"`) {
+		t.Error("Render() should contain first portal ID")
+	}
+	if !contains(result, `bf-pi="bf-portal-2"`) {
+		t.Error("Render() should contain second portal ID")
+	}
+	if !contains(result, `bf-po="scope-1"`) {
+		t.Error("Render() should contain first portal owner")
+	}
+	if !contains(result, `bf-po="scope-2"`) {
+		t.Error("Render() should contain second portal owner")
+	}
+	if !contains(result, "<div>Content 1</div>") {
+		t.Error("Render() should contain first portal content")
+	}
+	if !contains(result, "<span>Content 2</span>") {
+		t.Error("Render() should contain second portal content")
+	}
+}
+
+func TestPortalCollector_AddKeyed(t *testing.T) {
+	pc := NewPortalCollector()
+	pc.AddKeyed("Tooltip_1", "tooltip-help", "<div>Help</div>")
+	pc.AddKeyed("Tooltip_2", "tooltip-help", "<div>Help (again)</div>")
+	pc.AddKeyed("Tooltip_3", "tooltip-info", "<div>Info</div>")
+
+	result := string(pc.Render())
+	expected := `<div bf-pi="bf-portal-1" bf-po="Tooltip_1"><div>Help</div></div>` + "\n" +
+		`<div bf-pi="bf-portal-2" bf-po="Tooltip_3"><div>Info</div></div>` + "\n"
+	if result != expected {
+		t.Errorf("Render() = %q, want %q", result, expected)
+	}
+}
+
+func TestPortalCollector_AddTo(t *testing.T) {
+	pc := NewPortalCollector()
+	pc.AddTo("Dialog_1", "#modal-root", "<div>Modal</div>")
+	pc.AddTo("Dialog_2", "", "<div>Body end</div>")
+
+	result := string(pc.Render())
+	expected := `<div bf-pi="bf-portal-1" bf-po="Dialog_1" bf-pt="#modal-root"><div>Modal</div></div>` + "\n" +
+		`<div bf-pi="bf-portal-2" bf-po="Dialog_2"><div>Body end</div></div>` + "\n"
+	if result != expected {
+		t.Errorf("Render() = %q, want %q", result, expected)
+	}
+}
+
+func TestPortalCollector_RenderStream(t *testing.T) {
+	pc := NewPortalCollector()
+	pc.Add("Modal_1", "<div>One</div>")
+	pc.AddTo("Dialog_1", `#root"x`, "<div>Two</div>")
+
+	var buf strings.Builder
+	if err := pc.RenderStream(&buf); err != nil {
+		t.Fatalf("RenderStream returned error: %v", err)
+	}
+	if got, want := buf.String(), string(pc.Render()); got != want {
+		t.Errorf("RenderStream = %q, want Render() output %q", got, want)
+	}
+
+	var nilPC *PortalCollector
+	if err := nilPC.RenderStream(&buf); err != nil {
+		t.Errorf("RenderStream on nil collector returned error: %v", err)
+	}
+
+	if err := pc.RenderStream(failingWriter{}); err == nil {
+		t.Error("RenderStream should return the write error")
+	}
+}
+
+type failingWriter struct{}
+
+func (failingWriter) Write(p []byte) (int, error) { return 0, io.ErrClosedPipe }
+
+func TestPortalCollector_ConcurrentAdd(t *testing.T) {
+	pc := NewPortalCollector()
+
+	const goroutines = 50
+	const perGoroutine = 20
+	var wg sync.WaitGroup
+	for g := 0; g < goroutines; g++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for i := 0; i < perGoroutine; i++ {
+				pc.Add("scope", "<div></div>")
+				pc.Render()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if len(pc.portals) != goroutines*perGoroutine {
+		t.Fatalf("portals count = %d, want %d", len(pc.portals), goroutines*perGoroutine)
+	}
+	seen := make(map[string]bool, len(pc.portals))
+	for _, p := range pc.portals {
+		if seen[p.ID] {
+			t.Fatalf("duplicate portal ID %q", p.ID)
+		}
+		seen[p.ID] = true
+	}
+}
+
+// =============================================================================
+// Script Collection Tests
+// =============================================================================
+
+func TestScriptCollector_ConcurrentRegister(t *testing.T) {
+	sc := NewScriptCollector()
+	srcs := []string{"/static/a.js", "/static/b.js", "/static/c.js", "/static/d.js"}
+
+	// Register the first script up front so insertion order is partly fixed.
+	sc.Register(srcs[0])
+
+	var wg sync.WaitGroup
+	for g := 0; g < 50; g++ {
+		wg.Add(1)
+		go func(g int) {
+			defer wg.Done()
+			for i := range srcs {
+				sc.Register(srcs[(g+i)%len(srcs)])
+				sc.Scripts()
+			}
+		}(g)
+	}
+	wg.Wait()
+
+	got := sc.Scripts()
+	if len(got) != len(srcs) {
+		t.Fatalf("Scripts() = %v, want %d deduplicated entries", got, len(srcs))
+	}
+	if got[0] != srcs[0] {
+		t.Errorf("Scripts()[0] = %q, want first-registered %q", got[0], srcs[0])
+	}
+	seen := make(map[string]bool)
+	for _, src := range got {
+		if seen[src] {
+			t.Errorf("Scripts() contains duplicate %q", src)
+		}
+		seen[src] = true
+	}
+}
+
+func TestScriptCollector_OrderPreserved(t *testing.T) {
+	sc := NewScriptCollector()
+	sc.Register("/b.js")
+	sc.Register("/a.js")
+	sc.Register("/b.js")
+
+	got := sc.Scripts()
+	if len(got) != 2 || got[0] != "/b.js" || got[1] != "/a.js" {
+		t.Errorf("Scripts() = %v, want [/b.js /a.js]", got)
+	}
+}
+
+func TestBfScripts_Plain(t *testing.T) {
+	sc := NewScriptCollector()
+	sc.Register("/static/app.js")
+
+	got := string(BfScripts(sc))
+	want := `<script type="module" src="/static/app.js"></script>` + "\n"
+	if got != want {
+		t.Errorf("BfScripts = %q, want %q", got, want)
+	}
+}
+
+func TestBfScripts_WithOptions(t *testing.T) {
+	sc := NewScriptCollector()
+	sc.RegisterWith("/static/analytics.js", ScriptOptions{Async: true, Nonce: "r4nd0m"})
+	sc.RegisterWith("/static/app.js", ScriptOptions{Defer: true})
+
+	got := string(BfScripts(sc))
+	want := `<script type="module" src="/static/analytics.js" async nonce="r4nd0m"></script>` + "\n" +
+		`<script type="module" src="/static/app.js" defer></script>` + "\n"
+	if got != want {
+		t.Errorf("BfScripts =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestScriptCollector_RegisterWithDedupBySrc(t *testing.T) {
+	sc := NewScriptCollector()
+	sc.RegisterWith("/static/app.js", ScriptOptions{Nonce: "first"})
+	sc.RegisterWith("/static/app.js", ScriptOptions{Nonce: "second", Async: true})
+	sc.Register("/static/app.js")
+
+	if got := sc.Scripts(); len(got) != 1 {
+		t.Fatalf("Scripts() = %v, want a single entry", got)
+	}
+	if got := sc.Options("/static/app.js"); got != (ScriptOptions{Nonce: "first"}) {
+		t.Errorf("Options() = %+v, want first registration's options", got)
+	}
+}
+
+func TestScriptCollector_RegisterInlineDedup(t *testing.T) {
+	sc := NewScriptCollector()
+	sc.RegisterInline("window.__a = 1")
+	sc.RegisterInline("window.__b = 2")
+	sc.RegisterInline("window.__a = 1")
+
+	got := sc.InlineScripts()
+	if len(got) != 2 || got[0] != "window.__a = 1" || got[1] != "window.__b = 2" {
+		t.Errorf("InlineScripts() = %v, want two deduplicated entries in order", got)
+	}
+}
+
+func TestBfScripts_InlineAfterExternal(t *testing.T) {
+	sc := NewScriptCollector()
+	sc.RegisterInline("console.log('a < b')")
+	sc.Register("/static/app.js")
+
+	got := string(BfScripts(sc))
+	want := `<script type="module" src="/static/app.js"></script>` + "\n" +
+		`<script type="module">console.log('a < b')</script>` + "\n"
+	if got != want {
+		t.Errorf("BfScripts =\n%q\nwant\n%q", got, want)
+	}
+}
+
+// helper function for string contains check
+func contains(s, substr string) bool {
+	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
+}
+
+// =============================================================================
+// Sort Tests
+// =============================================================================
+
+type sortItem struct {
+	Name     string
+	Priority int
+	Price    float64
+}
+
+func TestSort_AscendingByInt(t *testing.T) {
+	items := []sortItem{
+		{Name: "C", Priority: 3},
+		{Name: "A", Priority: 1},
+		{Name: "B", Priority: 2},
+	}
+
+	result := Sort(items, "priority", "asc")
+
+	if len(result) != 3 {
+		t.Fatalf("Sort returned %d items, want 3", len(result))
+	}
+	if result[0].(sortItem).Name != "A" {
+		t.Errorf("Sort asc: first item = %v, want A", result[0].(sortItem).Name)
+	}
+	if result[1].(sortItem).Name != "B" {
+		t.Errorf("Sort asc: second item = %v, want B", result[1].(sortItem).Name)
+	}
+	if result[2].(sortItem).Name != "C" {
+		t.Errorf("Sort asc: third item = %v, want C", result[2].(sortItem).Name)
+	}
+}
+
+func TestSort_DescendingByInt(t *testing.T) {
+	items := []sortItem{
+		{Name: "A", Priority: 1},
+		{Name: "C", Priority: 3},
+		{Name: "B", Priority: 2},
+	}
+
+	result := Sort(items, "priority", "desc")
+
+	if len(result) != 3 {
+		t.Fatalf("Sort returned %d items, want 3", len(result))
+	}
+	if result[0].(sortItem).Name != "C" {
+		t.Errorf("Sort desc: first item = %v, want C", result[0].(sortItem).Name)
+	}
+	if result[2].(sortItem).Name != "A" {
+		t.Errorf("Sort desc: last item = %v, want A", result[2].(sortItem).Name)
+	}
+}
+
+func TestSort_ByFloat(t *testing.T) {
+	items := []sortItem{
+		{Name: "Expensive", Price: 99.99},
+		{Name: "Cheap", Price: 9.99},
+		{Name: "Mid", Price: 49.99},
+	}
+
+	result := Sort(items, "price", "asc")
+
+	if len(result) != 3 {
+		t.Fatalf("Sort returned %d items, want 3", len(result))
+	}
+	if result[0].(sortItem).Name != "Cheap" {
+		t.Errorf("Sort by float asc: first = %v, want Cheap", result[0].(sortItem).Name)
+	}
+	if result[2].(sortItem).Name != "Expensive" {
+		t.Errorf("Sort by float asc: last = %v, want Expensive", result[2].(sortItem).Name)
+	}
+}
+
+func TestSort_EmptySlice(t *testing.T) {
+	var items []sortItem
+	result := Sort(items, "priority", "asc")
+
+	if result == nil {
+		t.Error("Sort of empty slice should return empty slice, not nil")
+	}
+	if len(result) != 0 {
+		t.Errorf("Sort of empty slice returned %d items, want 0", len(result))
+	}
+}
+
+func TestSort_NilSlice(t *testing.T) {
+	result := Sort(nil, "priority", "asc")
+
+	if result != nil {
+		t.Errorf("Sort of nil should return nil, got %v", result)
+	}
+}
+
+func TestSort_NonMutating(t *testing.T) {
+	items := []sortItem{
+		{Name: "C", Priority: 3},
+		{Name: "A", Priority: 1},
+		{Name: "B", Priority: 2},
+	}
+
+	Sort(items, "priority", "asc")
+
+	// Original slice should be unchanged
+	if items[0].Name != "C" {
+		t.Errorf("Sort mutated original: first = %v, want C", items[0].Name)
+	}
+}
+
+func TestSortValues_IntDescending(t *testing.T) {
+	items := []int{3, 1, 10, 2}
+	got := SortValues(items, "desc")
+
+	if want := []any{10, 3, 2, 1}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SortValues(desc) = %v, want %v", got, want)
+	}
+	if items[0] != 3 {
+		t.Errorf("SortValues mutated original: first = %v, want 3", items[0])
+	}
+}
+
+func TestSortValues_StringAscending(t *testing.T) {
+	got := SortValues([]string{"pear", "apple", "Banana", "apple"}, "asc")
+
+	if want := []any{"Banana", "apple", "apple", "pear"}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SortValues(asc) = %v, want %v", got, want)
+	}
+}
+
+func TestSortValues_Float(t *testing.T) {
+	got := SortValues([]float64{2.5, -1, 0.25}, "asc")
+
+	if want := []any{-1.0, 0.25, 2.5}; !reflect.DeepEqual(got, want) {
+		t.Errorf("SortValues(floats) = %v, want %v", got, want)
+	}
+}
+
+func TestSortValues_EmptyAndNil(t *testing.T) {
+	if got := SortValues([]int{}, "asc"); got == nil || len(got) != 0 {
+		t.Errorf("SortValues of empty slice = %#v, want empty non-nil slice", got)
+	}
+	if got := SortValues(nil, "asc"); got != nil {
+		t.Errorf("SortValues of nil = %v, want nil", got)
+	}
+}
+
+func containsHelper(s, substr string) bool {
+	for i := 0; i <= len(s)-len(substr); i++ {
+		if s[i:i+len(substr)] == substr {
+			return true
+		}
+	}
+	return false
+}
+
+// =============================================================================
+// GroupSums Tests
+// =============================================================================
+
+type groupItem struct {
+	Category string
+	Amount   any
+}
+
+func TestGroupSums_TwoGroups(t *testing.T) {
+	items := []groupItem{
+		{Category: "food", Amount: 10},
+		{Category: "travel", Amount: 120.5},
+		{Category: "food", Amount: 2.25},
+		{Category: "travel", Amount: int64(30)},
+	}
+
+	got := GroupSums(items, "category", "amount")
+
+	if len(got) != 2 {
+		t.Fatalf("GroupSums returned %d groups, want 2", len(got))
+	}
+	if got["food"] != 12.25 {
+		t.Errorf("GroupSums food = %v, want 12.25", got["food"])
+	}
+	if got["travel"] != 150.5 {
+		t.Errorf("GroupSums travel = %v, want 150.5", got["travel"])
+	}
+}
+
+func TestGroupSums_NonSlice(t *testing.T) {
+	got := GroupSums(nil, "category", "amount")
+	if got == nil || len(got) != 0 {
+		t.Errorf("GroupSums(nil) = %v, want empty map", got)
+	}
+}
+
+// =============================================================================
+// Renderer Tests
+// =============================================================================
+
+type renderTestProps struct {
+	ScopeID  string
+	Title    string
+	Scripts  *ScriptCollector
+	Portals  *PortalCollector
+	BfIsRoot bool
+}
+
+func TestRenderer_RenderTo(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Page"}}{{.Scripts.Register "/static/page.js"}}<h1>{{.Title}}</h1>{{end}}`)
+	layout := func(ctx *RenderContext) string {
+		return "<title>" + ctx.Title + "</title>" + string(ctx.ComponentHTML) + string(ctx.Scripts)
+	}
+	r := NewRenderer(tmpl, layout)
+
+	rec := httptest.NewRecorder()
+	err := r.RenderTo(rec, RenderOptions{
+		ComponentName: "Page",
+		Props:         &renderTestProps{ScopeID: "Page_1", Title: "Hello"},
+	})
+	if err != nil {
+		t.Fatalf("RenderTo returned error: %v", err)
+	}
+
+	want := `<title>Page - BarefootJS</title><h1>Hello</h1><script type="module" src="/static/page.js"></script>` + "\n"
+	if rec.Body.String() != want {
+		t.Errorf("RenderTo body = %q, want %q", rec.Body.String(), want)
+	}
+
+	// RenderTo and Render produce the same page
+	if got := r.Render(RenderOptions{ComponentName: "Page", Props: &renderTestProps{Title: "Hello"}}); got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+}
+
+type localeKey struct{}
+
+func TestRenderer_RenderCtx(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Page"}}<h1>{{.Title}}</h1>{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string {
+		locale, _ := ctx.Ctx.Value(localeKey{}).(string)
+		return `<html lang="` + locale + `">` + string(ctx.ComponentHTML) + "</html>"
+	})
+
+	ctx := context.WithValue(context.Background(), localeKey{}, "ja")
+	got, err := r.RenderCtx(ctx, RenderOptions{ComponentName: "Page", Props: &renderTestProps{Title: "Hello"}})
+	if err != nil {
+		t.Fatalf("RenderCtx returned error: %v", err)
+	}
+	if want := `<html lang="ja"><h1>Hello</h1></html>`; got != want {
+		t.Errorf("RenderCtx = %q, want %q", got, want)
+	}
+
+	// Other render methods expose a non-nil background context
+	if got := r.Render(RenderOptions{ComponentName: "Page", Props: &renderTestProps{Title: "Hi"}}); got != `<html lang=""><h1>Hi</h1></html>` {
+		t.Errorf("Render = %q", got)
+	}
+}
+
+func TestRenderer_RenderCtxCancelled(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Page"}}ok{{end}}`)
+	layoutCalled := false
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string {
+		layoutCalled = true
+		return string(ctx.ComponentHTML)
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	got, err := r.RenderCtx(ctx, RenderOptions{ComponentName: "Page", Props: &renderTestProps{}})
+	if err != context.Canceled {
+		t.Errorf("RenderCtx error = %v, want context.Canceled", err)
+	}
+	if got != "" || layoutCalled {
+		t.Errorf("cancelled RenderCtx should not render, got %q (layout called: %v)", got, layoutCalled)
+	}
+}
+
+func TestRenderer_FuncsOverride(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Price"}}{{bf_currency .Amount "$" 2}}{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+	props := func() interface{} { return &struct{ Amount float64 }{1234.5} }
+
+	// Execute the base set first: overrides must still work afterwards
+	if got := r.Render(RenderOptions{ComponentName: "Price", Props: props()}); got != "$1,234.50" {
+		t.Fatalf("base Render = %q, want $1,234.50", got)
+	}
+
+	euro := template.FuncMap{"bf_currency": func(v any, symbol string, places int) string {
+		return strings.ReplaceAll(Currency(v, "", places), ",", ".") + " €"
+	}}
+	got, err := r.RenderE(RenderOptions{ComponentName: "Price", Props: props(), Funcs: euro})
+	if err != nil {
+		t.Fatalf("RenderE with Funcs returned error: %v", err)
+	}
+	if got != "1.234.50 €" {
+		t.Errorf("override Render = %q, want 1.234.50 €", got)
+	}
+
+	// The shared set is unaffected
+	if got := r.Render(RenderOptions{ComponentName: "Price", Props: props()}); got != "$1,234.50" {
+		t.Errorf("base Render after override = %q, want $1,234.50", got)
+	}
+}
+
+func TestRenderer_FuncsSeesTemplatesAddedAfterNewRenderer(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Price"}}{{bf_currency .Amount "$" 2}}{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+	template.Must(tmpl.New("Total").Parse(`Total: {{bf_currency .Amount "$" 2}}`))
+
+	yen := template.FuncMap{"bf_currency": func(v any, symbol string, places int) string {
+		return Currency(v, "¥", 0)
+	}}
+	got, err := r.RenderE(RenderOptions{ComponentName: "Total", Props: &struct{ Amount float64 }{1234}, Funcs: yen})
+	if err != nil {
+		t.Fatalf("RenderE with Funcs returned error: %v", err)
+	}
+	if got != "Total: ¥1,234" {
+		t.Errorf("Render = %q, want Total: ¥1,234", got)
+	}
+}
+
+func TestRenderer_FuncsAfterExternalExecute(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Price"}}{{bf_currency .Amount "$" 2}}{{end}}`)
+	if err := tmpl.ExecuteTemplate(io.Discard, "Price", &struct{ Amount float64 }{1}); err != nil {
+		t.Fatalf("warm-up execute: %v", err)
+	}
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+
+	funcs := template.FuncMap{"bf_currency": func(v any, symbol string, places int) string { return "x" }}
+	_, err := r.RenderE(RenderOptions{ComponentName: "Price", Props: &struct{ Amount float64 }{1}, Funcs: funcs})
+	if err == nil || !strings.Contains(err.Error(), "executed before NewRenderer") {
+		t.Errorf("RenderE error = %v, want executed before NewRenderer", err)
+	}
+}
+
+func TestRenderer_RenderMulti(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Counter"}}{{.Scripts.Register "/static/runtime.js"}}{{.Scripts.Register "/static/counter.js"}}<div bf-s="{{bfScopeAttr .}}" {{bfPropsAttr .}}></div>{{end}}`+
+		`{{define "Toggle"}}{{.Scripts.Register "/static/runtime.js"}}{{.Scripts.Register "/static/toggle.js"}}<aside bf-s="{{bfScopeAttr .}}" {{bfPropsAttr .}}></aside>{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string {
+		return "<title>" + ctx.Title + "</title>" + string(ctx.ComponentHTML) + string(ctx.Scripts)
+	})
+
+	counter := &renderTestProps{ScopeID: "Counter_1"}
+	toggle := &renderTestProps{ScopeID: "Toggle_1"}
+	got, err := r.RenderMulti([]RenderOptions{
+		{ComponentName: "Counter", Props: counter, Title: "Dashboard"},
+		{ComponentName: "Toggle", Props: toggle},
+	})
+	if err != nil {
+		t.Fatalf("RenderMulti returned error: %v", err)
+	}
+
+	for _, want := range []string{
+		"<title>Dashboard</title>",
+		`<div bf-s="Counter_1" bf-p="`,
+		`<aside bf-s="Toggle_1" bf-p="`,
+	} {
+		if !contains(got, want) {
+			t.Errorf("RenderMulti output missing %q:\n%s", want, got)
+		}
+	}
+	if !counter.BfIsRoot || !toggle.BfIsRoot {
+		t.Error("every component should be marked as a root")
+	}
+	if counter.Scripts != toggle.Scripts {
+		t.Error("roots should share one ScriptCollector")
+	}
+	if n := strings.Count(got, `src="/static/runtime.js"`); n != 1 {
+		t.Errorf("shared script emitted %d times, want 1", n)
+	}
+	if !contains(got, `src="/static/counter.js"`) || !contains(got, `src="/static/toggle.js"`) {
+		t.Errorf("per-root scripts missing:\n%s", got)
+	}
+
+	if _, err := r.RenderMulti(nil); err == nil {
+		t.Error("RenderMulti with no components should return an error")
+	}
+	if _, err := r.RenderMulti([]RenderOptions{{ComponentName: "Missing", Props: &renderTestProps{}}}); err == nil {
+		t.Error("RenderMulti with unknown template should return an error")
+	}
+}
+
+func TestRenderer_PostProcess(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Page"}}<p>{{.Title}}</p>{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string {
+		return "<head>%csrf%</head>" + string(ctx.ComponentHTML)
+	})
+	postProcess := func(html string) string {
+		return strings.Replace(html, "%csrf%", "%CSRF%", 1)
+	}
+	want := "<head>%CSRF%</head><p>hi</p>"
+
+	if got := r.Render(RenderOptions{ComponentName: "Page", Props: &renderTestProps{Title: "hi"}, PostProcess: postProcess}); got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+	var buf strings.Builder
+	if err := r.RenderTo(&buf, RenderOptions{ComponentName: "Page", Props: &renderTestProps{Title: "hi"}, PostProcess: postProcess}); err != nil || buf.String() != want {
+		t.Errorf("RenderTo = %q, %v; want %q", buf.String(), err, want)
+	}
+	got, err := r.RenderMulti([]RenderOptions{{ComponentName: "Page", Props: &renderTestProps{Title: "hi"}, PostProcess: postProcess}})
+	if err != nil || got != want {
+		t.Errorf("RenderMulti = %q, %v; want %q", got, err, want)
+	}
+
+	if got := r.Render(RenderOptions{ComponentName: "Page", Props: &renderTestProps{Title: "hi"}}); got != "<head>%csrf%</head><p>hi</p>" {
+		t.Errorf("Render without PostProcess = %q", got)
+	}
+}
+
+func TestRenderer_Head(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Page"}}ok{{end}}`)
+	var head template.HTML
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string {
+		head = ctx.Head
+		return "<head>" + string(ctx.Head) + "</head>"
+	})
+
+	preload := template.HTML(`<link rel="preload" href="/static/font.woff2" as="font">`)
+	og := template.HTML(`<meta property="og:title" content="Page">`)
+	r.Render(RenderOptions{
+		ComponentName: "Page",
+		Props:         &renderTestProps{},
+		Head:          []template.HTML{preload, og, preload, ""},
+	})
+	if want := preload + "\n" + og; head != want {
+		t.Errorf("RenderContext.Head = %q, want %q", head, want)
+	}
+
+	// RenderMulti merges head entries from every component
+	got, err := r.RenderMulti([]RenderOptions{
+		{ComponentName: "Page", Props: &renderTestProps{}, Head: []template.HTML{preload}},
+		{ComponentName: "Page", Props: &renderTestProps{}, Head: []template.HTML{preload, og}},
+	})
+	if err != nil {
+		t.Fatalf("RenderMulti returned error: %v", err)
+	}
+	if want := "<head>" + string(preload) + "\n" + string(og) + "</head>"; got != want {
+		t.Errorf("RenderMulti = %q, want %q", got, want)
+	}
+}
+
+func TestRenderer_RenderToTemplateError(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Page"}}ok{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+
+	rec := httptest.NewRecorder()
+	if err := r.RenderTo(rec, RenderOptions{ComponentName: "Missing", Props: &renderTestProps{}}); err == nil {
+		t.Error("RenderTo with unknown template should return an error")
+	}
+}
+
+func TestMustLoadTemplates(t *testing.T) {
+	dir := t.TempDir()
+	src := `{{define "Page"}}<p>{{bf_upper .Title}}</p>{{end}}`
+	if err := os.WriteFile(filepath.Join(dir, "page.tmpl"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRenderer(MustLoadTemplates(filepath.Join(dir, "*.tmpl")), func(ctx *RenderContext) string {
+		return string(ctx.ComponentHTML)
+	})
+	if got := r.Render(RenderOptions{ComponentName: "Page", Props: &renderTestProps{Title: "hi"}}); got != "<p>HI</p>" {
+		t.Errorf("Render = %q, want <p>HI</p>", got)
+	}
+
+	defer func() {
+		if recover() == nil {
+			t.Error("MustLoadTemplates should panic when no files match")
+		}
+	}()
+	MustLoadTemplates(filepath.Join(dir, "*.missing"))
+}
+
+func TestMustLoadTemplatesDelims(t *testing.T) {
+	dir := t.TempDir()
+	src := `[[define "Page"]]<p bf-s="[[bfScopeAttr .]]">[[bf_upper .Title]] {{ clientValue }}</p>[[end]]`
+	if err := os.WriteFile(filepath.Join(dir, "page.tmpl"), []byte(src), 0o644); err != nil {
+		t.Fatal(err)
+	}
+
+	r := NewRenderer(MustLoadTemplatesDelims(filepath.Join(dir, "*.tmpl"), "[[", "]]"), func(ctx *RenderContext) string {
+		return string(ctx.ComponentHTML)
+	})
+	opts := RenderOptions{ComponentName: "Page", Props: &renderTestProps{ScopeID: "Page_1", Title: "hi"}}
+	want := `<p bf-s="Page_1">HI {{ clientValue }}</p>`
+	if got := r.Render(opts); got != want {
+		t.Errorf("Render = %q, want %q", got, want)
+	}
+
+	// Per-render clones keep the custom delimiters.
+	opts.Props = &renderTestProps{ScopeID: "Page_1", Title: "hi"}
+	opts.Funcs = template.FuncMap{"bf_upper": func(s string) string { return s + "!" }}
+	if got, want := r.Render(opts), `<p bf-s="Page_1">hi! {{ clientValue }}</p>`; got != want {
+		t.Errorf("Render with Funcs = %q, want %q", got, want)
+	}
+}
+
+func BenchmarkRenderer_TemplateReuse(b *testing.B) {
+	dir := b.TempDir()
+	for i := 0; i < 20; i++ {
+		src := fmt.Sprintf(`{{define "C%d"}}<div bf-s="{{bfScopeAttr .}}">{{.Title}}</div>{{end}}`, i)
+		if err := os.WriteFile(filepath.Join(dir, fmt.Sprintf("c%d.tmpl", i)), []byte(src), 0o644); err != nil {
+			b.Fatal(err)
+		}
+	}
+	glob := filepath.Join(dir, "*.tmpl")
+	layout := func(ctx *RenderContext) string { return string(ctx.ComponentHTML) }
+	opts := func() RenderOptions {
+		return RenderOptions{ComponentName: "C0", Props: &renderTestProps{ScopeID: "C0_1", Title: "t"}}
 	}
+
+	b.Run("parse per request", func(b *testing.B) {
+		for i := 0; i < b.N; i++ {
+			NewRenderer(MustLoadTemplates(glob), layout).Render(opts())
+		}
+	})
+	b.Run("cached", func(b *testing.B) {
+		r := NewRenderer(MustLoadTemplates(glob), layout)
+		b.ResetTimer()
+		for i := 0; i < b.N; i++ {
+			r.Render(opts())
+		}
+	})
 }
 
-func TestPortalHTML_Conditional(t *testing.T) {
-	data := struct {
-		Open bool
-	}{Open: true}
+type renderChildProps struct {
+	ScopeID   string
+	Label     string
+	Scripts   *ScriptCollector
+	Portals   *PortalCollector
+	BfIsChild bool
+}
 
-	result := PortalHTML(data, `<div data-state="{{if .Open}}open{{else}}closed{{end}}"></div>`)
-	expected := template.HTML(`<div data-state="open"></div>`)
-	if result != expected {
-		t.Errorf("PortalHTML conditional = %q, want %q", result, expected)
+func TestRenderer_PointerElementChildSlice(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "List"}}{{range .Items}}{{if .}}<li bf-s="{{bfScopeAttr .}}">{{.Label}}</li>{{end}}{{end}}{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+
+	props := &struct {
+		ScopeID string
+		Items   []*renderChildProps
+		Scripts *ScriptCollector
+		Portals *PortalCollector
+	}{
+		ScopeID: "List_1",
+		Items: []*renderChildProps{
+			nil,
+			{ScopeID: "Item_1", Label: "a"},
+			{ScopeID: "Item_2", Label: "b"},
+		},
 	}
 
-	// Test with Open = false
-	data.Open = false
-	result = PortalHTML(data, `<div data-state="{{if .Open}}open{{else}}closed{{end}}"></div>`)
-	expected = template.HTML(`<div data-state="closed"></div>`)
-	if result != expected {
-		t.Errorf("PortalHTML conditional (false) = %q, want %q", result, expected)
+	got := r.Render(RenderOptions{ComponentName: "List", Props: props})
+
+	for _, item := range props.Items[1:] {
+		if item.Scripts != props.Scripts || item.Scripts == nil {
+			t.Errorf("%s: Scripts not set to the page collector", item.ScopeID)
+		}
+		if item.Portals != props.Portals || item.Portals == nil {
+			t.Errorf("%s: Portals not set to the page collector", item.ScopeID)
+		}
+		if !item.BfIsChild {
+			t.Errorf("%s: BfIsChild should be true", item.ScopeID)
+		}
+	}
+	if want := `<li bf-s="~Item_1">a</li><li bf-s="~Item_2">b</li>`; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
 	}
 }
 
-func TestPortalHTML_InvalidTemplate(t *testing.T) {
-	result := PortalHTML(nil, "{{.Unclosed")
-	// Should return error comment instead of panicking
-	if !contains(string(result), "bfPortalHTML error") {
-		t.Errorf("PortalHTML invalid template should return error comment, got %q", result)
+func TestRenderer_DetectCollisions(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "List"}}{{range .Items}}<li bf-s="{{bfScopeAttr .}}">{{.Label}}</li>{{end}}{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+
+	newProps := func() interface{} {
+		return &struct {
+			ScopeID string
+			Items   []renderChildProps
+			Scripts *ScriptCollector
+		}{
+			ScopeID: "List_1",
+			Items: []renderChildProps{
+				{ScopeID: "Item_1", Label: "a"},
+				{ScopeID: "Item_2", Label: "b"},
+				{ScopeID: "Item_1", Label: "c"},
+			},
+		}
+	}
+
+	_, err := r.RenderE(RenderOptions{ComponentName: "List", Props: newProps(), DetectCollisions: true})
+	if err == nil || !strings.Contains(err.Error(), `"Item_1"`) {
+		t.Errorf("RenderE error = %v, want duplicate ScopeID \"Item_1\"", err)
+	}
+
+	if _, err := r.RenderE(RenderOptions{ComponentName: "List", Props: newProps()}); err != nil {
+		t.Errorf("RenderE without DetectCollisions returned error: %v", err)
 	}
 }
 
-// =============================================================================
-// Portal Collection Tests
-// =============================================================================
+type validateTestProps struct {
+	ScopeID string
+	Items   []renderChildProps
+	Scripts *ScriptCollector
+}
 
-func TestNewPortalCollector(t *testing.T) {
-	pc := NewPortalCollector()
-	if pc == nil {
-		t.Error("NewPortalCollector() returned nil")
+func TestValidate(t *testing.T) {
+	complete := &validateTestProps{
+		ScopeID: "List_1",
+		Items:   []renderChildProps{{ScopeID: "Item_1"}, {ScopeID: "Item_2"}},
 	}
-	if len(pc.portals) != 0 {
-		t.Errorf("NewPortalCollector() should have empty portals, got %d", len(pc.portals))
+	if err := Validate(complete); err != nil {
+		t.Errorf("Validate(complete) = %v, want nil", err)
 	}
-	if pc.counter != 0 {
-		t.Errorf("NewPortalCollector() counter should be 0, got %d", pc.counter)
+
+	missing := &validateTestProps{
+		Items: []renderChildProps{{ScopeID: "Item_1"}, {Label: "no id"}},
+	}
+	err := Validate(missing)
+	if err == nil {
+		t.Fatal("Validate should fail when ScopeID is missing")
+	}
+	want := "bf: invalid props: root (*bf.validateTestProps): missing ScopeID; child 2 (*bf.renderChildProps): missing ScopeID"
+	if err.Error() != want {
+		t.Errorf("Validate error =\n%s\nwant\n%s", err, want)
+	}
+
+	noScripts := &struct{ ScopeID string }{ScopeID: "X_1"}
+	if err := Validate(noScripts); err == nil || !strings.Contains(err.Error(), "missing Scripts field") {
+		t.Errorf("Validate(no Scripts) = %v, want missing Scripts field", err)
+	}
+
+	if err := Validate("not props"); err == nil {
+		t.Error("Validate(string) should fail")
 	}
 }
 
-func TestPortalCollector_Add(t *testing.T) {
-	pc := NewPortalCollector()
+func TestRenderer_Validate(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "List"}}<ul bf-s="{{bfScopeAttr .}}"></ul>{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
 
-	// Add first portal
-	result := pc.Add("scope-1", "<div>Content 1</div>")
-	if result != "" {
-		t.Errorf("Add() should return empty string, got %q", result)
+	if _, err := r.RenderE(RenderOptions{ComponentName: "List", Props: &validateTestProps{}, Validate: true}); err == nil {
+		t.Error("RenderE with Validate should fail for props without ScopeID")
 	}
-	if len(pc.portals) != 1 {
-		t.Errorf("After first Add(), portals count should be 1, got %d", len(pc.portals))
+	got, err := r.RenderE(RenderOptions{ComponentName: "List", Props: &validateTestProps{ScopeID: "List_1"}, Validate: true})
+	if err != nil || got != `<ul bf-s="List_1"></ul>` {
+		t.Errorf("RenderE = %q, %v; want valid render", got, err)
 	}
-	if pc.portals[0].ID != "bf-portal-1" {
-		t.Errorf("First portal ID should be 'bf-portal-1', got %q", pc.portals[0].ID)
+}
+
+type defaultsTestProps struct {
+	ScopeID  string
+	PageSize int     `bf:"default=20"`
+	Sort     string  `bf:"default=newest first"`
+	Open     bool    `bf:"default=true"`
+	Ratio    float64 `bf:"default=0.5"`
+	Limit    uint8   `bf:"default=7"`
+	Plain    int
+	Scripts  *ScriptCollector
+}
+
+func TestApplyDefaults(t *testing.T) {
+	p := &defaultsTestProps{}
+	if err := ApplyDefaults(p); err != nil {
+		t.Fatalf("ApplyDefaults error: %v", err)
 	}
-	if pc.portals[0].OwnerID != "scope-1" {
-		t.Errorf("First portal OwnerID should be 'scope-1', got %q", pc.portals[0].OwnerID)
+	want := defaultsTestProps{PageSize: 20, Sort: "newest first", Open: true, Ratio: 0.5, Limit: 7}
+	if *p != want {
+		t.Errorf("ApplyDefaults = %+v, want %+v", *p, want)
 	}
 
-	// Add second portal
-	pc.Add("scope-2", "<div>Content 2</div>")
-	if len(pc.portals) != 2 {
-		t.Errorf("After second Add(), portals count should be 2, got %d", len(pc.portals))
+	explicit := &defaultsTestProps{PageSize: 5, Sort: "oldest"}
+	if err := ApplyDefaults(explicit); err != nil {
+		t.Fatalf("ApplyDefaults error: %v", err)
 	}
-	if pc.portals[1].ID != "bf-portal-2" {
-		t.Errorf("Second portal ID should be 'bf-portal-2', got %q", pc.portals[1].ID)
+	if explicit.PageSize != 5 || explicit.Sort != "oldest" {
+		t.Errorf("explicit values overridden: PageSize=%d Sort=%q", explicit.PageSize, explicit.Sort)
 	}
 }
 
-func TestPortalCollector_Render_Empty(t *testing.T) {
-	pc := NewPortalCollector()
-	result := pc.Render()
-	if result != "" {
-		t.Errorf("Render() on empty collector should return empty string, got %q", result)
+func TestApplyDefaults_Errors(t *testing.T) {
+	bad := &struct {
+		N int `bf:"default=many"`
+	}{}
+	if err := ApplyDefaults(bad); err == nil || !strings.Contains(err.Error(), "field N") {
+		t.Errorf("ApplyDefaults(unparseable) = %v, want error naming field N", err)
 	}
-}
 
-func TestPortalCollector_Render_Nil(t *testing.T) {
-	var pc *PortalCollector
-	result := pc.Render()
-	if result != "" {
-		t.Errorf("Render() on nil collector should return empty string, got %q", result)
+	unsupported := &struct {
+		Tags []string `bf:"default=a"`
+	}{}
+	if err := ApplyDefaults(unsupported); err == nil {
+		t.Error("ApplyDefaults should reject unsupported field types")
+	}
+
+	if err := ApplyDefaults(defaultsTestProps{}); err == nil {
+		t.Error("ApplyDefaults should reject non-pointer props")
 	}
 }
 
-func TestPortalCollector_Render_Single(t *testing.T) {
-	pc := NewPortalCollector()
-	pc.Add("scope-abc", "<div>Portal Content</div>")
+func TestParseBfTag(t *testing.T) {
+	tests := []struct {
+		tag  reflect.StructTag
+		want bfTagOptions
+	}{
+		{``, bfTagOptions{}},
+		{`bf:"server"`, bfTagOptions{server: true}},
+		{`bf:"default=20"`, bfTagOptions{hasDefault: true, def: "20"}},
+		{`bf:"server,default=a,b"`, bfTagOptions{server: true, hasDefault: true, def: "a,b"}},
+		{`bf:"default="`, bfTagOptions{hasDefault: true}},
+		{`bf:"unknown,server"`, bfTagOptions{server: true}},
+	}
 
-	result := string(pc.Render())
-	expected := `<div bf-pi="bf-portal-1" bf-po="scope-abc"><div>Portal Content</div></div>` + "\n"
-	if result != expected {
-		t.Errorf("Render() = %q, want %q", result, expected)
+	for _, tt := range tests {
+		if got := parseBfTag(reflect.StructField{Tag: tt.tag}); got != tt.want {
+			t.Errorf("parseBfTag(%s) = %+v, want %+v", tt.tag, got, tt.want)
+		}
 	}
 }
 
-func TestPortalCollector_Render_Multiple(t *testing.T) {
-	pc := NewPortalCollector()
-	pc.Add("scope-1", "<div>Content 1</div>")
-	pc.Add("scope-2", "<span>Content 2</span>")
-
-	result := string(pc.Render())
+func TestApplyDefaults_ServerField(t *testing.T) {
+	props := &struct {
+		ScopeID string `json:"scopeID"`
+		Region  string `json:"region" bf:"server,default=us,east"`
+	}{ScopeID: "Map_1"}
 
-	// Check that both portals are rendered
-	if !contains(result, `bf-pi="bf-portal-1"`) {
-		t.Error("Render() should contain first portal ID")
+	if err := ApplyDefaults(props); err != nil {
+		t.Fatalf("ApplyDefaults error: %v", err)
 	}
-	if !contains(result, `bf-pi="bf-portal-2"`) {
-		t.Error("Render() should contain second portal ID")
+	if props.Region != "us,east" {
+		t.Errorf("Region = %q, want %q", props.Region, "us,east")
 	}
-	if !contains(result, `bf-po="scope-1"`) {
-		t.Error("Render() should contain first portal owner")
+	data, err := MarshalPropsStable(props)
+	if err != nil {
+		t.Fatal(err)
 	}
-	if !contains(result, `bf-po="scope-2"`) {
-		t.Error("Render() should contain second portal owner")
+	if want := `{"scopeID":"Map_1"}`; string(data) != want {
+		t.Errorf("MarshalPropsStable = %s, want %s", data, want)
 	}
-	if !contains(result, "<div>Content 1</div>") {
-		t.Error("Render() should contain first portal content")
+}
+
+func TestRenderer_ApplyDefaults(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "List"}}{{.PageSize}} {{.Sort}}{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+
+	got, err := r.RenderE(RenderOptions{ComponentName: "List", Props: &defaultsTestProps{Sort: "a-z"}, ApplyDefaults: true})
+	if err != nil || got != "20 a-z" {
+		t.Errorf("RenderE = %q, %v; want %q", got, err, "20 a-z")
 	}
-	if !contains(result, "<span>Content 2</span>") {
-		t.Error("Render() should contain second portal content")
+	if got, _ := r.RenderE(RenderOptions{ComponentName: "List", Props: &defaultsTestProps{}}); got != "0 " {
+		t.Errorf("RenderE without ApplyDefaults = %q, want %q", got, "0 ")
 	}
 }
 
-// helper function for string contains check
-func contains(s, substr string) bool {
-	return len(s) >= len(substr) && (s == substr || len(s) > 0 && containsHelper(s, substr))
+type embeddedBaseProps struct {
+	ScopeID   string
+	Items     []*renderChildProps
+	Scripts   *ScriptCollector
+	Portals   *PortalCollector
+	BfIsRoot  bool
+	BfIsChild bool
 }
 
-// =============================================================================
-// Sort Tests
-// =============================================================================
-
-type sortItem struct {
-	Name     string
-	Priority int
-	Price    float64
-}
+func TestRenderer_EmbeddedBaseChildren(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Page"}}<main bf-s="{{bfScopeAttr .}}">{{range .Items}}<li bf-s="{{bfScopeAttr .}}">{{.Label}}</li>{{end}}</main>{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
 
-func TestSort_AscendingByInt(t *testing.T) {
-	items := []sortItem{
-		{Name: "C", Priority: 3},
-		{Name: "A", Priority: 1},
-		{Name: "B", Priority: 2},
+	props := &struct {
+		embeddedBaseProps
+		Title string
+	}{
+		embeddedBaseProps: embeddedBaseProps{
+			ScopeID: "Page_1",
+			Items: []*renderChildProps{
+				{ScopeID: "Item_1", Label: "a"},
+				{ScopeID: "Item_2", Label: "b"},
+			},
+		},
+		Title: "Todos",
 	}
 
-	result := Sort(items, "priority", "asc")
+	got := r.Render(RenderOptions{ComponentName: "Page", Props: props})
 
-	if len(result) != 3 {
-		t.Fatalf("Sort returned %d items, want 3", len(result))
+	for _, item := range props.Items {
+		if item.Scripts == nil || item.Scripts != props.Scripts {
+			t.Errorf("%s: Scripts not set to the page collector", item.ScopeID)
+		}
+		if !item.BfIsChild {
+			t.Errorf("%s: BfIsChild should be true", item.ScopeID)
+		}
 	}
-	if result[0].(sortItem).Name != "A" {
-		t.Errorf("Sort asc: first item = %v, want A", result[0].(sortItem).Name)
+	if props.BfIsChild || !props.BfIsRoot {
+		t.Errorf("root flags: BfIsChild=%v BfIsRoot=%v, want false/true", props.BfIsChild, props.BfIsRoot)
 	}
-	if result[1].(sortItem).Name != "B" {
-		t.Errorf("Sort asc: second item = %v, want B", result[1].(sortItem).Name)
+	if want := `<main bf-s="Page_1"><li bf-s="~Item_1">a</li><li bf-s="~Item_2">b</li></main>`; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
 	}
-	if result[2].(sortItem).Name != "C" {
-		t.Errorf("Sort asc: third item = %v, want C", result[2].(sortItem).Name)
+}
+
+func TestComponentFields_SelfEmbedding(t *testing.T) {
+	type node struct {
+		*node
+		Name string
+	}
+	n := &node{Name: "a"}
+	n.node = n
+	if got := len(componentFields(reflect.ValueOf(n).Elem())); got != 1 {
+		t.Errorf("componentFields returned %d fields, want 1", got)
 	}
 }
 
-func TestSort_DescendingByInt(t *testing.T) {
-	items := []sortItem{
-		{Name: "A", Priority: 1},
-		{Name: "C", Priority: 3},
-		{Name: "B", Priority: 2},
+func TestRenderer_MapChildComponents(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Board"}}{{range $k, $c := .Columns}}<section bf-s="{{bfScopeAttr $c}}">{{$k}}</section>{{end}}{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+
+	props := &struct {
+		ScopeID  string
+		Columns  map[string]renderChildProps
+		Pinned   map[string]*renderChildProps
+		Scripts  *ScriptCollector
+		Portals  *PortalCollector
+		BfIsRoot bool
+	}{
+		ScopeID: "Board_1",
+		Columns: map[string]renderChildProps{
+			"todo": {ScopeID: "Column_todo"},
+			"done": {ScopeID: "Column_done"},
+		},
+		Pinned: map[string]*renderChildProps{
+			"top": {ScopeID: "Column_top"},
+		},
 	}
 
-	result := Sort(items, "priority", "desc")
+	got := r.Render(RenderOptions{ComponentName: "Board", Props: props})
 
-	if len(result) != 3 {
-		t.Fatalf("Sort returned %d items, want 3", len(result))
+	for key, col := range props.Columns {
+		if col.Scripts == nil || col.Scripts != props.Scripts {
+			t.Errorf("Columns[%s]: Scripts not set to the page collector", key)
+		}
+		if col.Portals == nil || col.Portals != props.Portals {
+			t.Errorf("Columns[%s]: Portals not set to the page collector", key)
+		}
+		if !col.BfIsChild {
+			t.Errorf("Columns[%s]: BfIsChild should be true", key)
+		}
 	}
-	if result[0].(sortItem).Name != "C" {
-		t.Errorf("Sort desc: first item = %v, want C", result[0].(sortItem).Name)
+	if pinned := props.Pinned["top"]; pinned.Scripts == nil || !pinned.BfIsChild {
+		t.Error("Pinned[top]: pointer map value should receive collectors and BfIsChild")
 	}
-	if result[2].(sortItem).Name != "A" {
-		t.Errorf("Sort desc: last item = %v, want A", result[2].(sortItem).Name)
+	if want := `<section bf-s="~Column_done">done</section><section bf-s="~Column_todo">todo</section>`; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
 	}
 }
 
-func TestSort_ByFloat(t *testing.T) {
-	items := []sortItem{
-		{Name: "Expensive", Price: 99.99},
-		{Name: "Cheap", Price: 9.99},
-		{Name: "Mid", Price: 49.99},
-	}
+type treeLeafProps struct {
+	ScopeID   string
+	Scripts   *ScriptCollector
+	Portals   *PortalCollector
+	BfIsChild bool
+}
 
-	result := Sort(items, "price", "asc")
+type treeBranchProps struct {
+	Leaf      treeLeafProps
+	ScopeID   string
+	Leaves    []treeLeafProps
+	Scripts   *ScriptCollector
+	Portals   *PortalCollector
+	BfIsChild bool
+}
 
-	if len(result) != 3 {
-		t.Fatalf("Sort returned %d items, want 3", len(result))
+type treeNodeProps struct {
+	ScopeID   string
+	Parent    *treeNodeProps
+	Children  []*treeNodeProps
+	Scripts   *ScriptCollector
+	Portals   *PortalCollector
+	BfIsChild bool
+	BfIsRoot  bool
+}
+
+func TestRenderer_RecursesIntoGrandchildren(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Root"}}{{range .Branches}}{{range .Leaves}}<i bf-s="{{bfScopeAttr .}}"></i>{{end}}{{end}}{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+
+	props := &struct {
+		ScopeID  string
+		Branches []treeBranchProps
+		Scripts  *ScriptCollector
+		Portals  *PortalCollector
+		BfIsRoot bool
+	}{
+		ScopeID: "Root_1",
+		Branches: []treeBranchProps{{
+			ScopeID: "Branch_1",
+			Leaf:    treeLeafProps{ScopeID: "Leaf_0"},
+			Leaves:  []treeLeafProps{{ScopeID: "Leaf_1"}, {ScopeID: "Leaf_2"}},
+		}},
 	}
-	if result[0].(sortItem).Name != "Cheap" {
-		t.Errorf("Sort by float asc: first = %v, want Cheap", result[0].(sortItem).Name)
+
+	got := r.Render(RenderOptions{ComponentName: "Root", Props: props})
+
+	branch := props.Branches[0]
+	for _, leaf := range append([]treeLeafProps{branch.Leaf}, branch.Leaves...) {
+		if leaf.Scripts == nil || leaf.Scripts != props.Scripts {
+			t.Errorf("%s: Scripts not set to the page collector", leaf.ScopeID)
+		}
+		if leaf.Portals == nil || leaf.Portals != props.Portals {
+			t.Errorf("%s: Portals not set to the page collector", leaf.ScopeID)
+		}
+		if !leaf.BfIsChild {
+			t.Errorf("%s: BfIsChild should be true", leaf.ScopeID)
+		}
 	}
-	if result[2].(sortItem).Name != "Expensive" {
-		t.Errorf("Sort by float asc: last = %v, want Expensive", result[2].(sortItem).Name)
+	if want := `<i bf-s="~Leaf_1"></i><i bf-s="~Leaf_2"></i>`; got != want {
+		t.Errorf("Render = %q, want %q", got, want)
 	}
 }
 
-func TestSort_EmptySlice(t *testing.T) {
-	var items []sortItem
-	result := Sort(items, "priority", "asc")
+func TestRenderer_SelfReferentialProps(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Tree"}}ok{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
 
-	if result == nil {
-		t.Error("Sort of empty slice should return empty slice, not nil")
+	root := &treeNodeProps{ScopeID: "Tree_1"}
+	child := &treeNodeProps{ScopeID: "Node_1", Parent: root}
+	child.Children = []*treeNodeProps{child} // direct cycle
+	root.Children = []*treeNodeProps{child}
+
+	if got := r.Render(RenderOptions{ComponentName: "Tree", Props: root}); got != "ok" {
+		t.Errorf("Render = %q, want ok", got)
 	}
-	if len(result) != 0 {
-		t.Errorf("Sort of empty slice returned %d items, want 0", len(result))
+	if !child.BfIsChild || child.Scripts != root.Scripts {
+		t.Error("child should receive collectors and BfIsChild")
+	}
+	if root.BfIsChild {
+		t.Error("root reached through a back-reference must not be marked as a child")
 	}
 }
 
-func TestSort_NilSlice(t *testing.T) {
-	result := Sort(nil, "priority", "asc")
+func TestRenderer_RenderFragment(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Todo"}}{{.Scripts.Register "/static/todo.js"}}<li bf-s="{{bfScopeAttr .}}">{{.Title}}</li>{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string {
+		return "<html><body>" + string(ctx.ComponentHTML) + string(ctx.Scripts) + "</body></html>"
+	})
 
-	if result != nil {
-		t.Errorf("Sort of nil should return nil, got %v", result)
+	got, err := r.RenderFragment("Todo", &renderTestProps{ScopeID: "Todo_1", Title: "Buy milk"})
+	if err != nil {
+		t.Fatalf("RenderFragment returned error: %v", err)
+	}
+	if !contains(string(got), `bf-s="Todo_1"`) {
+		t.Errorf("fragment should carry the bf-s scope attribute, got %q", got)
+	}
+	if contains(string(got), "<html>") || contains(string(got), "<script") {
+		t.Errorf("fragment should not include layout or scripts, got %q", got)
 	}
-}
 
-func TestSort_NonMutating(t *testing.T) {
-	items := []sortItem{
-		{Name: "C", Priority: 3},
-		{Name: "A", Priority: 1},
-		{Name: "B", Priority: 2},
+	if _, err := r.RenderFragment("Missing", &renderTestProps{}); err == nil {
+		t.Error("RenderFragment with unknown template should return an error")
 	}
+}
 
-	Sort(items, "priority", "asc")
+type fragmentTodoProps struct {
+	ScopeID   string           `json:"scopeID"`
+	Title     string           `json:"title"`
+	Scripts   *ScriptCollector `json:"-"`
+	Portals   *PortalCollector `json:"-"`
+	BfIsRoot  bool             `json:"-"`
+	BfIsChild bool             `json:"-"`
+}
 
-	// Original slice should be unchanged
-	if items[0].Name != "C" {
-		t.Errorf("Sort mutated original: first = %v, want C", items[0].Name)
+func TestRenderer_RenderFragmentChild(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "TodoItem"}}<li bf-s="{{bfScopeAttr .}}" {{bfPropsAttr .}}>{{.Title}}</li>{{end}}`+
+		`{{define "TodoList"}}<ul bf-s="{{bfScopeAttr .}}">{{range .Items}}{{template "TodoItem" .}}{{end}}</ul>{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+
+	page := r.Render(RenderOptions{ComponentName: "TodoList", Props: &struct {
+		ScopeID string
+		Items   []fragmentTodoProps
+		Scripts *ScriptCollector
+	}{
+		ScopeID: "TodoList_1",
+		Items:   []fragmentTodoProps{{ScopeID: "TodoItem_3", Title: "Buy milk"}},
+	}})
+
+	got, err := r.RenderFragmentChild("TodoItem", &fragmentTodoProps{ScopeID: "TodoItem_3", Title: "Buy milk"})
+	if err != nil {
+		t.Fatalf("RenderFragmentChild returned error: %v", err)
+	}
+	if want := `<li bf-s="~TodoItem_3" >Buy milk</li>`; string(got) != want {
+		t.Errorf("RenderFragmentChild = %q, want %q", got, want)
+	}
+	if !contains(page, string(got)) {
+		t.Errorf("child fragment %q does not match the item in the full render %q", got, page)
+	}
+
+	root, err := r.RenderFragment("TodoItem", &fragmentTodoProps{ScopeID: "TodoItem_3", Title: "Buy milk"})
+	if err != nil {
+		t.Fatalf("RenderFragment returned error: %v", err)
+	}
+	if contains(page, string(root)) {
+		t.Errorf("root fragment %q should differ from the child markup in %q", root, page)
 	}
 }
 
-func containsHelper(s, substr string) bool {
-	for i := 0; i <= len(s)-len(substr); i++ {
-		if s[i:i+len(substr)] == substr {
-			return true
-		}
+func TestRenderer_Partial(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Nav"}}<nav>{{range .}}<a href="{{.}}">{{bf_upper .}}</a>{{end}}</nav>{{end}}{{define "Title"}}<h1>{{.Title}}</h1>{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+
+	got, err := r.Partial("Nav", []string{"/a", "/b"})
+	if err != nil {
+		t.Fatalf("Partial returned error: %v", err)
+	}
+	if want := template.HTML(`<nav><a href="/a">/A</a><a href="/b">/B</a></nav>`); got != want {
+		t.Errorf("Partial = %q, want %q", got, want)
+	}
+
+	props := &renderTestProps{ScopeID: "Page_1", Title: "Home"}
+	if got, err := r.Partial("Title", props); err != nil || got != "<h1>Home</h1>" {
+		t.Errorf("Partial(Title) = %q, %v; want <h1>Home</h1>", got, err)
+	}
+	if props.Scripts != nil || props.Portals != nil || props.BfIsRoot {
+		t.Error("Partial should not inject collectors or root markers")
+	}
+
+	if _, err := r.Partial("Missing", nil); err == nil {
+		t.Error("Partial with unknown template should return an error")
 	}
-	return false
 }
@@ -0,0 +1,67 @@
+// Package bfecho adapts a bf.Renderer to Echo's echo.Renderer interface.
+//
+// It lives in its own module so the core bf module stays free of the Echo
+// dependency; only applications that import bfecho pull Echo into their
+// module graph.
+package bfecho
+
+import (
+	"io"
+
+	"github.com/barefootjs/runtime/bf"
+	"github.com/labstack/echo/v4"
+)
+
+// EchoRenderer implements echo.Renderer on top of a bf.Renderer.
+//
+// Usage:
+//
+//	e.Renderer = bfecho.NewEchoRenderer(renderer)
+//	return c.Render(http.StatusOK, "Counter", props)
+type EchoRenderer struct {
+	renderer *bf.Renderer
+}
+
+// NewEchoRenderer wraps renderer for use as an Echo renderer.
+func NewEchoRenderer(renderer *bf.Renderer) *EchoRenderer {
+	return &EchoRenderer{renderer: renderer}
+}
+
+// Render renders the component named name into w.
+//
+// data may be:
+//   - a bf.RenderOptions value (ComponentName is overridden by name)
+//   - a map[string]interface{} with optional "Props", "Title", "Heading"
+//     and "Extra" keys
+//   - any other value, used directly as the component props
+func (r *EchoRenderer) Render(w io.Writer, name string, data interface{}, c echo.Context) error {
+	opts := echoRenderOptions(data)
+	opts.ComponentName = name
+	return r.renderer.RenderTo(w, opts)
+}
+
+func echoRenderOptions(data interface{}) bf.RenderOptions {
+	switch d := data.(type) {
+	case bf.RenderOptions:
+		return d
+	case *bf.RenderOptions:
+		if d != nil {
+			return *d
+		}
+		return bf.RenderOptions{}
+	case map[string]interface{}:
+		opts := bf.RenderOptions{Props: d["Props"]}
+		if title, ok := d["Title"].(string); ok {
+			opts.Title = title
+		}
+		if heading, ok := d["Heading"].(string); ok {
+			opts.Heading = heading
+		}
+		if extra, ok := d["Extra"].(map[string]interface{}); ok {
+			opts.Extra = extra
+		}
+		return opts
+	default:
+		return bf.RenderOptions{Props: data}
+	}
+}
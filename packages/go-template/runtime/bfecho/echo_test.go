@@ -0,0 +1,73 @@
+package bfecho
+
+import (
+	"html/template"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/barefootjs/runtime/bf"
+	"github.com/labstack/echo/v4"
+)
+
+type handlerTestProps struct {
+	ScopeID  string
+	Name     string
+	Scripts  *bf.ScriptCollector
+	Portals  *bf.PortalCollector
+	BfIsRoot bool
+}
+
+func newEchoTestRenderer(t *testing.T) *bf.Renderer {
+	t.Helper()
+	tmpl := template.Must(template.New("").Funcs(bf.FuncMap()).Parse(`{{define "Greeting"}}<p bf-s="{{bfScopeAttr .}}">Hello {{.Name}}</p>{{end}}`))
+	return bf.NewRenderer(tmpl, func(ctx *bf.RenderContext) string {
+		return "<title>" + ctx.Title + "</title><h1>" + ctx.Heading + "</h1>" + string(ctx.ComponentHTML)
+	})
+}
+
+func TestEchoRenderer(t *testing.T) {
+	e := echo.New()
+	e.Renderer = NewEchoRenderer(newEchoTestRenderer(t))
+
+	tests := []struct {
+		name string
+		data interface{}
+		want string
+	}{
+		{
+			"props only",
+			&handlerTestProps{ScopeID: "Greeting_1", Name: "Ada"},
+			`<title>Greeting - BarefootJS</title><h1></h1><p bf-s="Greeting_1">Hello Ada</p>`,
+		},
+		{
+			"data map",
+			map[string]interface{}{
+				"Props":   &handlerTestProps{ScopeID: "Greeting_1", Name: "Ada"},
+				"Title":   "Hi",
+				"Heading": "Greeting",
+			},
+			`<title>Hi</title><h1>Greeting</h1><p bf-s="Greeting_1">Hello Ada</p>`,
+		},
+		{
+			"render options",
+			bf.RenderOptions{Props: &handlerTestProps{ScopeID: "Greeting_1", Name: "Ada"}, Title: "Hi"},
+			`<title>Hi</title><h1></h1><p bf-s="Greeting_1">Hello Ada</p>`,
+		},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			rec := httptest.NewRecorder()
+			c := e.NewContext(httptest.NewRequest(http.MethodGet, "/", nil), rec)
+			if err := c.Render(http.StatusOK, "Greeting", tt.data); err != nil {
+				t.Fatalf("Render error: %v", err)
+			}
+			if rec.Code != http.StatusOK {
+				t.Errorf("status = %d, want 200", rec.Code)
+			}
+			if rec.Body.String() != tt.want {
+				t.Errorf("body = %q, want %q", rec.Body.String(), tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,125 @@
+// Package bftest provides golden-file helpers for testing server-rendered
+// BarefootJS components.
+//
+// AssertRender renders a component through a bf.Renderer and compares the
+// page with a golden file. Output is normalized first (whitespace between
+// tags collapsed, attributes sorted by name) so cosmetic template changes
+// and map-ordered attributes don't cause flaky diffs.
+//
+// Example:
+//
+//	func TestCounter(t *testing.T) {
+//	    props := NewCounterProps(CounterInput{Initial: 3})
+//	    bftest.AssertRender(t, renderer, bf.RenderOptions{
+//	        ComponentName: "Counter",
+//	        Props:         &props,
+//	    }, "testdata/counter.golden")
+//	}
+//
+// Run `BFTEST_UPDATE=1 go test` to (re)write golden files from the current
+// output, or wire Update to the test package's own -update flag:
+//
+//	var update = flag.Bool("update", false, "update golden files")
+//
+//	func TestMain(m *testing.M) {
+//	    flag.Parse()
+//	    bftest.Update = *update
+//	    os.Exit(m.Run())
+//	}
+package bftest
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+	"testing"
+
+	"github.com/barefootjs/runtime/bf"
+)
+
+// UpdateEnv is the environment variable that, when non-empty, makes
+// AssertRender rewrite golden files.
+const UpdateEnv = "BFTEST_UPDATE"
+
+// Update makes AssertRender rewrite golden files instead of comparing
+// against them. The package registers no flags of its own, so a test package
+// can set it from its own -update flag.
+var Update bool
+
+// updating reports whether golden files should be rewritten.
+func updating() bool {
+	return Update || os.Getenv(UpdateEnv) != ""
+}
+
+// AssertRender renders opts with renderer and compares the normalized page
+// with the golden file at goldenPath. When Update is set or BFTEST_UPDATE is
+// non-empty, the golden file (and its directory) is written instead and the
+// assertion passes.
+func AssertRender(t *testing.T, renderer *bf.Renderer, opts bf.RenderOptions, goldenPath string) {
+	t.Helper()
+
+	html, err := renderer.RenderE(opts)
+	if err != nil {
+		t.Fatalf("bftest: render %s: %v", opts.ComponentName, err)
+	}
+	got := Normalize(html)
+
+	if updating() {
+		if err := os.MkdirAll(filepath.Dir(goldenPath), 0o755); err != nil {
+			t.Fatalf("bftest: %v", err)
+		}
+		if err := os.WriteFile(goldenPath, []byte(got+"\n"), 0o644); err != nil {
+			t.Fatalf("bftest: %v", err)
+		}
+		return
+	}
+
+	golden, err := os.ReadFile(goldenPath)
+	if err != nil {
+		t.Fatalf("bftest: read golden file: %v (run with BFTEST_UPDATE=1 to create it)", err)
+	}
+	if want := Normalize(string(golden)); got != want {
+		t.Errorf("bftest: %s does not match %s (run with BFTEST_UPDATE=1 to accept)\ngot:\n%s\nwant:\n%s",
+			opts.ComponentName, goldenPath, got, want)
+	}
+}
+
+var (
+	// betweenTags matches whitespace-only text between two tags.
+	betweenTags = regexp.MustCompile(`>\s+<`)
+
+	// startTag matches an HTML start tag and captures its name, attribute
+	// list and optional self-closing slash.
+	startTag = regexp.MustCompile(`<([a-zA-Z][a-zA-Z0-9-]*)((?:\s+[^\s"'=/>]+(?:\s*=\s*(?:"[^"]*"|'[^']*'|[^\s"'>]+))?)*)\s*(/?)>`)
+
+	// attribute matches one attribute within a start tag's attribute list.
+	attribute = regexp.MustCompile(`([^\s"'=/>]+)(?:\s*=\s*("[^"]*"|'[^']*'|[^\s"'>]+))?`)
+)
+
+// Normalize rewrites rendered HTML into a canonical form for comparison:
+// whitespace between tags is removed, the result is trimmed, and each start
+// tag's attributes are sorted by name and rewritten as name=value pairs
+// separated by single spaces.
+// Text content, comments (including hydration markers) and attribute values
+// are left unchanged.
+func Normalize(html string) string {
+	html = betweenTags.ReplaceAllString(strings.TrimSpace(html), "><")
+	return startTag.ReplaceAllStringFunc(html, func(tag string) string {
+		m := startTag.FindStringSubmatch(tag)
+		attrs := attribute.FindAllStringSubmatch(m[2], -1)
+		sort.SliceStable(attrs, func(i, j int) bool { return attrs[i][1] < attrs[j][1] })
+
+		var b strings.Builder
+		b.WriteString("<" + m[1])
+		for _, a := range attrs {
+			b.WriteString(" " + a[1])
+			if a[2] != "" {
+				b.WriteString("=" + a[2])
+			}
+		}
+		b.WriteString(m[3] + ">")
+		return b.String()
+	})
+}
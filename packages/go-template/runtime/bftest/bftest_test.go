@@ -0,0 +1,103 @@
+package bftest
+
+import (
+	"flag"
+	"html/template"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/barefootjs/runtime/bf"
+)
+
+// update uses the standard golden-file flag idiom; bftest must not register
+// a conflicting flag of its own.
+var update = flag.Bool("update", false, "update golden files")
+
+func TestMain(m *testing.M) {
+	flag.Parse()
+	Update = *update
+	os.Exit(m.Run())
+}
+
+type greetingProps struct {
+	ScopeID  string              `json:"scopeID"`
+	Name     string              `json:"name"`
+	BfIsRoot bool                `json:"-"`
+	Scripts  *bf.ScriptCollector `json:"-"`
+}
+
+func newGreetingRenderer(t *testing.T, src string) *bf.Renderer {
+	t.Helper()
+	tmpl := template.Must(template.New("").Funcs(bf.FuncMap()).Parse(src))
+	return bf.NewRenderer(tmpl, func(ctx *bf.RenderContext) string {
+		return "<main>\n  " + string(ctx.ComponentHTML) + "\n</main>"
+	})
+}
+
+func greetingOpts() bf.RenderOptions {
+	return bf.RenderOptions{
+		ComponentName: "Greeting",
+		Props:         &greetingProps{ScopeID: "Greeting_1", Name: "Ada"},
+	}
+}
+
+func TestAssertRender(t *testing.T) {
+	r := newGreetingRenderer(t, `{{define "Greeting"}}<p class="greeting" bf-s="{{bfScopeAttr .}}" {{bfPropsAttr .}}>
+	Hello {{.Name}}
+</p>{{end}}`)
+	AssertRender(t, r, greetingOpts(), "testdata/greeting.golden")
+
+	// Attribute order and whitespace between tags are insignificant.
+	reordered := newGreetingRenderer(t, `{{define "Greeting"}}<p {{bfPropsAttr .}}  bf-s="{{bfScopeAttr .}}" class="greeting">
+	Hello {{.Name}}
+</p>{{end}}`)
+	AssertRender(t, reordered, greetingOpts(), "testdata/greeting.golden")
+}
+
+func TestAssertRender_Update(t *testing.T) {
+	Update = true
+	defer func() { Update = false }()
+
+	golden := filepath.Join(t.TempDir(), "new", "greeting.golden")
+	r := newGreetingRenderer(t, `{{define "Greeting"}}<p>Hi {{.Name}}</p>{{end}}`)
+	AssertRender(t, r, greetingOpts(), golden)
+
+	data, err := os.ReadFile(golden)
+	if err != nil {
+		t.Fatalf("golden file not written: %v", err)
+	}
+	if want := "<main><p>Hi Ada</p></main>\n"; string(data) != want {
+		t.Errorf("golden = %q, want %q", data, want)
+	}
+}
+
+func TestAssertRender_UpdateEnv(t *testing.T) {
+	t.Setenv(UpdateEnv, "1")
+
+	golden := filepath.Join(t.TempDir(), "greeting.golden")
+	r := newGreetingRenderer(t, `{{define "Greeting"}}<p>Hi {{.Name}}</p>{{end}}`)
+	AssertRender(t, r, greetingOpts(), golden)
+
+	if _, err := os.Stat(golden); err != nil {
+		t.Errorf("golden file not written with %s set: %v", UpdateEnv, err)
+	}
+}
+
+func TestNormalize(t *testing.T) {
+	tests := []struct {
+		in   string
+		want string
+	}{
+		{"  <div>\n  <span>a b</span>\n</div>\n", "<div><span>a b</span></div>"},
+		{`<a  href = "/x"   class='c' data-id=3 hidden>`, `<a class='c' data-id=3 hidden href="/x">`},
+		{`<input type="text" disabled />`, `<input disabled type="text"/>`},
+		{`<!--bf-x--><b z="1" a="2">t</b><!--/bf-x-->`, `<!--bf-x--><b a="2" z="1">t</b><!--/bf-x-->`},
+	}
+
+	for _, tt := range tests {
+		if got := Normalize(tt.in); got != tt.want {
+			t.Errorf("Normalize(%q) = %q, want %q", tt.in, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,40 @@
+// Package bf — net/http integration
+//
+// Adapts a Renderer to the standard library's http.Handler so apps on
+// net/http (or any router accepting http.HandlerFunc) get the collector
+// and child-component wiring without reimplementing it per app.
+package bf
+
+import (
+	"io"
+	"net/http"
+)
+
+// HandlerFunc returns an http.HandlerFunc that renders componentName as a
+// full page. propsFn builds the component props for each request and must
+// return a fresh pointer to a props struct (collectors are injected into it).
+//
+// On success the page is written with status 200 and Content-Type
+// text/html; charset=utf-8. If the component template fails to execute,
+// the handler responds 500 with the error message.
+//
+// Example usage:
+//
+//	http.Handle("/counter", renderer.HandlerFunc("Counter", func(r *http.Request) interface{} {
+//	    return &CounterProps{ScopeID: "Counter_1", Initial: 0}
+//	}))
+func (r *Renderer) HandlerFunc(componentName string, propsFn func(*http.Request) interface{}) http.HandlerFunc {
+	return func(w http.ResponseWriter, req *http.Request) {
+		html, err := r.RenderE(RenderOptions{
+			ComponentName: componentName,
+			Props:         propsFn(req),
+		})
+		if err != nil {
+			http.Error(w, err.Error(), http.StatusInternalServerError)
+			return
+		}
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		w.WriteHeader(http.StatusOK)
+		io.WriteString(w, html)
+	}
+}
@@ -0,0 +1,60 @@
+package bf
+
+import (
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+type handlerTestProps struct {
+	ScopeID  string
+	Name     string
+	Scripts  *ScriptCollector
+	Portals  *PortalCollector
+	BfIsRoot bool
+}
+
+func TestRendererHandlerFunc(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Greeting"}}<p bf-s="{{bfScopeAttr .}}">Hello {{.Name}}</p>{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string {
+		return "<html><body>" + string(ctx.ComponentHTML) + "</body></html>"
+	})
+
+	h := r.HandlerFunc("Greeting", func(req *http.Request) interface{} {
+		return &handlerTestProps{ScopeID: "Greeting_1", Name: req.URL.Query().Get("name")}
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/?name=Ada", nil))
+
+	if rec.Code != http.StatusOK {
+		t.Errorf("status = %d, want 200", rec.Code)
+	}
+	if ct := rec.Header().Get("Content-Type"); ct != "text/html; charset=utf-8" {
+		t.Errorf("Content-Type = %q, want text/html; charset=utf-8", ct)
+	}
+	want := `<html><body><p bf-s="Greeting_1">Hello Ada</p></body></html>`
+	if rec.Body.String() != want {
+		t.Errorf("body = %q, want %q", rec.Body.String(), want)
+	}
+}
+
+func TestRendererHandlerFunc_TemplateError(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Broken"}}{{.Missing.Field}}{{end}}`)
+	r := NewRenderer(tmpl, func(ctx *RenderContext) string { return string(ctx.ComponentHTML) })
+
+	h := r.HandlerFunc("Broken", func(req *http.Request) interface{} {
+		return &handlerTestProps{}
+	})
+
+	rec := httptest.NewRecorder()
+	h(rec, httptest.NewRequest("GET", "/", nil))
+
+	if rec.Code != http.StatusInternalServerError {
+		t.Errorf("status = %d, want 500", rec.Code)
+	}
+	if !strings.Contains(rec.Body.String(), "Missing") {
+		t.Errorf("body should contain the template error, got %q", rec.Body.String())
+	}
+}
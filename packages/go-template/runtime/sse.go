@@ -0,0 +1,79 @@
+// Package bf — Server-Sent Events helpers
+//
+// SSEStream pushes re-rendered component fragments to the client as
+// Server-Sent Events, so a page can patch a scope in place (e.g., a todo
+// list kept in sync across tabs).
+package bf
+
+import (
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// SSEUpdateEvent is the SSE event name used for fragment updates.
+const SSEUpdateEvent = "bf-update"
+
+// SSEStream formats rendered fragments as Server-Sent Events.
+type SSEStream struct {
+	renderer *Renderer
+}
+
+// NewSSEStream creates an SSEStream that renders fragments with renderer.
+func NewSSEStream(renderer *Renderer) *SSEStream {
+	return &SSEStream{renderer: renderer}
+}
+
+// Event renders the component via RenderFragment and frames it as a
+// bf-update event:
+//
+//	event: bf-update
+//	id: <ScopeID>
+//	data: <first line>
+//	data: <second line>
+//
+// Each line of the fragment becomes its own data: line, which the browser's
+// EventSource joins back with "\n", so multi-line HTML survives the framing.
+// The id: line carries the props' ScopeID (omitted when empty) so the client
+// knows which bf-s scope to patch.
+func (s *SSEStream) Event(componentName string, props interface{}) (string, error) {
+	fragment, err := s.renderer.RenderFragment(componentName, props)
+	if err != nil {
+		return "", err
+	}
+
+	var b strings.Builder
+	b.WriteString("event: " + SSEUpdateEvent + "\n")
+	if id := getStringField(props, "ScopeID"); id != "" {
+		b.WriteString("id: " + sseLine(id) + "\n")
+	}
+	data := strings.ReplaceAll(string(fragment), "\r\n", "\n")
+	data = strings.ReplaceAll(data, "\r", "\n")
+	for _, line := range strings.Split(data, "\n") {
+		b.WriteString("data: " + line + "\n")
+	}
+	b.WriteString("\n")
+	return b.String(), nil
+}
+
+// Send renders the component, writes the event to w, and flushes it when w
+// implements http.Flusher. The caller is responsible for setting the
+// text/event-stream response headers.
+func (s *SSEStream) Send(w http.ResponseWriter, componentName string, props interface{}) error {
+	event, err := s.Event(componentName, props)
+	if err != nil {
+		return err
+	}
+	if _, err := fmt.Fprint(w, event); err != nil {
+		return err
+	}
+	if flusher, ok := w.(http.Flusher); ok {
+		flusher.Flush()
+	}
+	return nil
+}
+
+// sseLine strips line breaks, which would otherwise end an SSE field early.
+func sseLine(s string) string {
+	return strings.NewReplacer("\r", "", "\n", "").Replace(s)
+}
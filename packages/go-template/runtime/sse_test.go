@@ -0,0 +1,45 @@
+package bf
+
+import (
+	"net/http/httptest"
+	"testing"
+)
+
+func TestSSEStreamEvent(t *testing.T) {
+	tmpl := mustParseTemplate(t, "{{define \"Todo\"}}<ul bf-s=\"{{bfScopeAttr .}}\">\n  <li>{{.Title}}</li>\r\n</ul>{{end}}")
+	s := NewSSEStream(NewRenderer(tmpl, func(ctx *RenderContext) string { return "" }))
+
+	got, err := s.Event("Todo", &renderTestProps{ScopeID: "TodoList_1", Title: "Buy milk"})
+	if err != nil {
+		t.Fatalf("Event returned error: %v", err)
+	}
+	want := "event: bf-update\n" +
+		"id: TodoList_1\n" +
+		"data: <ul bf-s=\"TodoList_1\">\n" +
+		"data:   <li>Buy milk</li>\n" +
+		"data: </ul>\n" +
+		"\n"
+	if got != want {
+		t.Errorf("Event =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSSEStreamSend(t *testing.T) {
+	tmpl := mustParseTemplate(t, `{{define "Todo"}}<li>{{.Title}}</li>{{end}}`)
+	s := NewSSEStream(NewRenderer(tmpl, func(ctx *RenderContext) string { return "" }))
+
+	rec := httptest.NewRecorder()
+	if err := s.Send(rec, "Todo", &renderTestProps{Title: "Walk"}); err != nil {
+		t.Fatalf("Send returned error: %v", err)
+	}
+	if want := "event: bf-update\ndata: <li>Walk</li>\n\n"; rec.Body.String() != want {
+		t.Errorf("body = %q, want %q", rec.Body.String(), want)
+	}
+	if !rec.Flushed {
+		t.Error("Send should flush the response")
+	}
+
+	if err := s.Send(rec, "Missing", &renderTestProps{}); err == nil {
+		t.Error("Send with unknown template should return an error")
+	}
+}
@@ -0,0 +1,530 @@
+// Package bf — UI markup helpers
+//
+// Small template functions that emit the static HTML structure of common
+// UI patterns (disclosure, tabs, steppers, ...). The markup carries the
+// same attributes the client components read on hydration, so the server
+// output and the hydrated state agree on first paint.
+package bf
+
+import (
+	"html/template"
+	"math"
+	"net/url"
+	"reflect"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Details renders a native <details>/<summary> disclosure block.
+// The summary text is HTML-escaped; content is trusted HTML.
+// When open is true the open attribute is emitted so the client sees the
+// same expanded state on hydration.
+//
+// Usage in Go templates:
+//
+//	{{bf_details "What is BarefootJS?" .AnswerHTML false}}
+func Details(summary string, content template.HTML, open bool) template.HTML {
+	var buf strings.Builder
+	buf.WriteString("<details")
+	if open {
+		buf.WriteString(" open")
+	}
+	buf.WriteString("><summary>")
+	buf.WriteString(template.HTMLEscapeString(summary))
+	buf.WriteString("</summary>")
+	buf.WriteString(string(content))
+	buf.WriteString("</details>")
+	return template.HTML(buf.String())
+}
+
+// Tab is a single tab rendered by Tabs.
+type Tab struct {
+	ID      string        // Tab identifier (used for data-value and element ids)
+	Label   string        // Trigger label (HTML-escaped)
+	Content template.HTML // Panel content (trusted HTML)
+}
+
+// Tabs renders a tab list followed by one panel per tab, marking the tab
+// whose ID equals active as selected. Mirrors the markup of the Tabs UI
+// component (data-slot/data-state/data-value) so the client hydrates
+// without a state mismatch. Triggers and panels are linked with
+// aria-controls/aria-labelledby; inactive panels are hidden.
+//
+// group scopes the generated element ids (<group>-tab-<ID> and
+// <group>-panel-<ID>) so several tab groups on one page never share an id;
+// pass a value unique to the page, such as the component's scope ID.
+//
+// Usage in Go templates:
+//
+//	{{bf_tabs "settings" .Tabs .ActiveTab}}
+func Tabs(group string, tabs []Tab, active string) template.HTML {
+	prefix := template.HTMLEscapeString(group)
+	var buf strings.Builder
+	buf.WriteString(`<div data-slot="tabs-list" role="tablist">`)
+	for _, tab := range tabs {
+		id := template.HTMLEscapeString(tab.ID)
+		selected := tab.ID == active
+		buf.WriteString(`<button type="button" data-slot="tabs-trigger" role="tab" id="`)
+		buf.WriteString(prefix)
+		buf.WriteString(`-tab-`)
+		buf.WriteString(id)
+		buf.WriteString(`" aria-controls="`)
+		buf.WriteString(prefix)
+		buf.WriteString(`-panel-`)
+		buf.WriteString(id)
+		if selected {
+			buf.WriteString(`" aria-selected="true" data-state="active" tabindex="0"`)
+		} else {
+			buf.WriteString(`" aria-selected="false" data-state="inactive" tabindex="-1"`)
+		}
+		buf.WriteString(` data-value="`)
+		buf.WriteString(id)
+		buf.WriteString(`">`)
+		buf.WriteString(template.HTMLEscapeString(tab.Label))
+		buf.WriteString(`</button>`)
+	}
+	buf.WriteString(`</div>`)
+
+	for _, tab := range tabs {
+		id := template.HTMLEscapeString(tab.ID)
+		selected := tab.ID == active
+		buf.WriteString(`<div data-slot="tabs-content" role="tabpanel" id="`)
+		buf.WriteString(prefix)
+		buf.WriteString(`-panel-`)
+		buf.WriteString(id)
+		buf.WriteString(`" aria-labelledby="`)
+		buf.WriteString(prefix)
+		buf.WriteString(`-tab-`)
+		buf.WriteString(id)
+		if selected {
+			buf.WriteString(`" data-state="active"`)
+		} else {
+			buf.WriteString(`" data-state="inactive" hidden`)
+		}
+		buf.WriteString(` data-value="`)
+		buf.WriteString(id)
+		buf.WriteString(`" tabindex="0">`)
+		buf.WriteString(string(tab.Content))
+		buf.WriteString(`</div>`)
+	}
+	return template.HTML(buf.String())
+}
+
+// Stepper renders a multi-step progress indicator as an ordered list.
+// current is the zero-based index of the active step and is clamped into
+// range. Steps before current get the "done" class, the current step
+// "active" (with aria-current="step"), and later steps "upcoming". Each
+// step carries an accessible label such as "Step 2 of 3: Shipping (current)".
+func Stepper(steps []string, current int) template.HTML {
+	if len(steps) == 0 {
+		return `<ol data-slot="stepper"></ol>`
+	}
+	if current < 0 {
+		current = 0
+	}
+	if current > len(steps)-1 {
+		current = len(steps) - 1
+	}
+
+	total := strconv.Itoa(len(steps))
+	var buf strings.Builder
+	buf.WriteString(`<ol data-slot="stepper">`)
+	for i, step := range steps {
+		state, status := "upcoming", "upcoming"
+		if i < current {
+			state, status = "done", "completed"
+		} else if i == current {
+			state, status = "active", "current"
+		}
+		label := template.HTMLEscapeString(step)
+		buf.WriteString(`<li class="`)
+		buf.WriteString(state)
+		buf.WriteString(`" data-state="`)
+		buf.WriteString(state)
+		buf.WriteString(`"`)
+		if i == current {
+			buf.WriteString(` aria-current="step"`)
+		}
+		buf.WriteString(` aria-label="Step `)
+		buf.WriteString(strconv.Itoa(i + 1))
+		buf.WriteString(` of `)
+		buf.WriteString(total)
+		buf.WriteString(`: `)
+		buf.WriteString(label)
+		buf.WriteString(` (`)
+		buf.WriteString(status)
+		buf.WriteString(`)">`)
+		buf.WriteString(label)
+		buf.WriteString(`</li>`)
+	}
+	buf.WriteString(`</ol>`)
+	return template.HTML(buf.String())
+}
+
+// Toast is a single notification rendered by ToastRegion.
+type Toast struct {
+	ID      string // Toast identifier, read by the client for dismiss/timeout
+	Variant string // Visual variant (e.g., "default", "success", "error")
+	Message string // Message text (HTML-escaped)
+}
+
+// ToastRegion renders a live region containing the given toasts. Each toast
+// carries bf-toast-id for the client's dismiss and timeout logic and a
+// "toast-{variant}" class ("toast-default" when Variant is empty).
+//
+// Toasts usually belong at the end of <body>; route the region through the
+// portal collector so it is emitted with the other portals:
+//
+//	{{.Portals.Add .ScopeID (bf_toasts .Toasts)}}
+func ToastRegion(toasts []Toast) template.HTML {
+	var buf strings.Builder
+	buf.WriteString(`<div data-slot="toast-provider" role="region" aria-label="Notifications" aria-live="polite">`)
+	for _, toast := range toasts {
+		variant := toast.Variant
+		if variant == "" {
+			variant = "default"
+		}
+		variant = template.HTMLEscapeString(variant)
+		buf.WriteString(`<div data-slot="toast" role="status" bf-toast-id="`)
+		buf.WriteString(template.HTMLEscapeString(toast.ID))
+		buf.WriteString(`" data-variant="`)
+		buf.WriteString(variant)
+		buf.WriteString(`" class="toast toast-`)
+		buf.WriteString(variant)
+		buf.WriteString(`">`)
+		buf.WriteString(template.HTMLEscapeString(toast.Message))
+		buf.WriteString(`</div>`)
+	}
+	buf.WriteString(`</div>`)
+	return template.HTML(buf.String())
+}
+
+// RatingInput renders an interactive star rating as a radio group. Each of
+// the max stars is a radio input carrying bf-rating-star="N" so the client
+// can wire hover and click handlers; the container carries bf-rating with
+// the field name. The star equal to value is pre-checked and every star up
+// to value has data-state="on". value is clamped into [0, max].
+func RatingInput(name string, value, max int) template.HTML {
+	if max < 0 {
+		max = 0
+	}
+	if value < 0 {
+		value = 0
+	}
+	if value > max {
+		value = max
+	}
+
+	escapedName := template.HTMLEscapeString(name)
+	var buf strings.Builder
+	buf.WriteString(`<div role="radiogroup" bf-rating="`)
+	buf.WriteString(escapedName)
+	buf.WriteString(`" data-value="`)
+	buf.WriteString(strconv.Itoa(value))
+	buf.WriteString(`">`)
+	for i := 1; i <= max; i++ {
+		n := strconv.Itoa(i)
+		buf.WriteString(`<input type="radio" name="`)
+		buf.WriteString(escapedName)
+		buf.WriteString(`" value="`)
+		buf.WriteString(n)
+		buf.WriteString(`" bf-rating-star="`)
+		buf.WriteString(n)
+		if i <= value {
+			buf.WriteString(`" data-state="on"`)
+		} else {
+			buf.WriteString(`" data-state="off"`)
+		}
+		buf.WriteString(` aria-label="`)
+		buf.WriteString(n)
+		if i == 1 {
+			buf.WriteString(` star"`)
+		} else {
+			buf.WriteString(` stars"`)
+		}
+		if i == value {
+			buf.WriteString(` checked`)
+		}
+		buf.WriteString(`>`)
+	}
+	buf.WriteString(`</div>`)
+	return template.HTML(buf.String())
+}
+
+// LoadMoreSentinel renders the infinite-scroll sentinel the client observes
+// to fetch the next page. Returns an empty fragment when nextURL is empty
+// (the last page).
+// Format: <div bf-load-more data-next="nextURL"></div>
+func LoadMoreSentinel(nextURL string) template.HTML {
+	if nextURL == "" {
+		return ""
+	}
+	return template.HTML(`<div bf-load-more data-next="` + template.HTMLEscapeString(nextURL) + `"></div>`)
+}
+
+var (
+	iconsMu sync.RWMutex
+	icons   = map[string]template.HTML{}
+)
+
+// RegisterIcon registers trusted SVG markup under name for use by IconButton.
+// Registering the same name again replaces the previous markup.
+// Safe for concurrent use; typically called once at startup.
+func RegisterIcon(name string, svg template.HTML) {
+	iconsMu.Lock()
+	defer iconsMu.Unlock()
+	icons[name] = svg
+}
+
+// lookupIcon returns the registered icon markup, or a placeholder span for
+// unknown names so a missing icon is visible but never breaks the layout.
+func lookupIcon(name string) template.HTML {
+	iconsMu.RLock()
+	svg, ok := icons[name]
+	iconsMu.RUnlock()
+	if ok {
+		return svg
+	}
+	return template.HTML(`<span data-icon-placeholder="` + template.HTMLEscapeString(name) + `" aria-hidden="true"></span>`)
+}
+
+// IconButton renders an icon-only <button> with an aria-label so screen
+// readers announce its purpose. The icon is looked up from RegisterIcon;
+// attrs are merged onto the button in sorted key order (values escaped)
+// and may override the default type="button". aria-label always comes
+// from label. Keys that are not valid attribute names (see Attr) are
+// skipped.
+func IconButton(iconName, label string, attrs map[string]string) template.HTML {
+	merged := map[string]string{"type": "button"}
+	for k, v := range attrs {
+		if k == "aria-label" || !attrNamePattern.MatchString(k) {
+			continue
+		}
+		merged[k] = v
+	}
+	keys := make([]string, 0, len(merged))
+	for k := range merged {
+		keys = append(keys, k)
+	}
+	sort.Strings(keys)
+
+	var buf strings.Builder
+	buf.WriteString(`<button`)
+	for _, k := range keys {
+		buf.WriteString(` `)
+		buf.WriteString(k)
+		buf.WriteString(`="`)
+		buf.WriteString(template.HTMLEscapeString(merged[k]))
+		buf.WriteString(`"`)
+	}
+	buf.WriteString(` aria-label="`)
+	buf.WriteString(template.HTMLEscapeString(label))
+	buf.WriteString(`">`)
+	buf.WriteString(string(lookupIcon(iconName)))
+	buf.WriteString(`</button>`)
+	return template.HTML(buf.String())
+}
+
+// OverlayTheme picks the text theme for content overlaid on an image whose
+// average color is avgHex ("#rgb" or "#rrggbb"). Returns "on-light" when dark
+// text has more contrast against the color (WCAG relative luminance) and
+// "on-dark" otherwise, including for invalid input.
+func OverlayTheme(avgHex string) string {
+	lum, ok := relativeLuminance(avgHex)
+	if !ok {
+		return "on-dark"
+	}
+	// Contrast ratios against black and white text.
+	contrastBlack := (lum + 0.05) / 0.05
+	contrastWhite := 1.05 / (lum + 0.05)
+	if contrastBlack > contrastWhite {
+		return "on-light"
+	}
+	return "on-dark"
+}
+
+// relativeLuminance returns the WCAG 2.x relative luminance of a hex color.
+func relativeLuminance(hex string) (float64, bool) {
+	hex = strings.TrimPrefix(strings.TrimSpace(hex), "#")
+	if len(hex) == 3 {
+		hex = string([]byte{hex[0], hex[0], hex[1], hex[1], hex[2], hex[2]})
+	}
+	if len(hex) != 6 {
+		return 0, false
+	}
+	rgb, err := strconv.ParseUint(hex, 16, 32)
+	if err != nil {
+		return 0, false
+	}
+
+	channel := func(c uint64) float64 {
+		v := float64(c) / 255
+		if v <= 0.03928 {
+			return v / 12.92
+		}
+		return math.Pow((v+0.055)/1.055, 2.4)
+	}
+	r := channel(rgb >> 16 & 0xff)
+	g := channel(rgb >> 8 & 0xff)
+	b := channel(rgb & 0xff)
+	return 0.2126*r + 0.7152*g + 0.0722*b, true
+}
+
+// Checklist renders items as a progress checklist. Each item's label is read
+// from labelField (HTML-escaped) and its completed state from the bool
+// doneField; completed items show a checked box and data-state="done",
+// others an empty box and data-state="pending".
+func Checklist(items any, labelField, doneField string) template.HTML {
+	v := reflect.ValueOf(items)
+	if v.Kind() != reflect.Slice && v.Kind() != reflect.Array {
+		return ""
+	}
+
+	capitalizedLabel := capitalize(labelField)
+	capitalizedDone := capitalize(doneField)
+
+	var buf strings.Builder
+	buf.WriteString(`<ul data-slot="checklist">`)
+	for i := 0; i < v.Len(); i++ {
+		item := v.Index(i).Interface()
+		done, _ := getFieldValue(item, capitalizedDone).(bool)
+		if done {
+			buf.WriteString(`<li data-state="done"><span role="img" aria-label="completed">☑</span> `)
+		} else {
+			buf.WriteString(`<li data-state="pending"><span role="img" aria-label="not completed">☐</span> `)
+		}
+		buf.WriteString(template.HTMLEscapeString(toString(getFieldValue(item, capitalizedLabel))))
+		buf.WriteString(`</li>`)
+	}
+	buf.WriteString(`</ul>`)
+	return template.HTML(buf.String())
+}
+
+// ImgWithFallback renders an <img> carrying data-bf-fallback so the client
+// can swap in fallbackSrc when the image fails to load. An empty src renders
+// the fallback image directly.
+func ImgWithFallback(src, alt, fallbackSrc string) template.HTML {
+	escapedAlt := template.HTMLEscapeString(alt)
+	if src == "" {
+		return template.HTML(`<img src="` + template.HTMLEscapeString(fallbackSrc) + `" alt="` + escapedAlt + `">`)
+	}
+	return template.HTML(`<img src="` + template.HTMLEscapeString(src) +
+		`" alt="` + escapedAlt +
+		`" data-bf-fallback="` + template.HTMLEscapeString(fallbackSrc) + `">`)
+}
+
+// SegmentOption is a single segment rendered by Segmented.
+type SegmentOption struct {
+	Value string // Segment value (data-value)
+	Label string // Button label (HTML-escaped)
+}
+
+// Segmented renders a segmented control as a button group, marking the
+// segment whose Value equals active with the "active" class,
+// aria-pressed="true" and data-state="on". Mirrors the ToggleGroup UI
+// component's data-slot/data-state markup for hydration. If no option
+// matches active, no segment is marked.
+func Segmented(options []SegmentOption, active string) template.HTML {
+	var buf strings.Builder
+	buf.WriteString(`<div data-slot="toggle-group" role="group">`)
+	for _, opt := range options {
+		buf.WriteString(`<button type="button" data-slot="toggle-group-item"`)
+		if opt.Value == active {
+			buf.WriteString(` class="active" data-state="on" aria-pressed="true"`)
+		} else {
+			buf.WriteString(` data-state="off" aria-pressed="false"`)
+		}
+		buf.WriteString(` data-value="`)
+		buf.WriteString(template.HTMLEscapeString(opt.Value))
+		buf.WriteString(`">`)
+		buf.WriteString(template.HTMLEscapeString(opt.Label))
+		buf.WriteString(`</button>`)
+	}
+	buf.WriteString(`</div>`)
+	return template.HTML(buf.String())
+}
+
+// GoalBar renders a progress bar comparing value against target. The fill
+// width is clamp(value/target, 0, 1) * 100%. The bar gets the "over" class
+// once value meets or exceeds target and "under" otherwise. A zero or
+// negative target renders a 0% "under" bar.
+func GoalBar(value, target any) template.HTML {
+	v, t := toFloat64(value), toFloat64(target)
+
+	ratio := 0.0
+	state := "under"
+	if t > 0 {
+		ratio = math.Max(0, math.Min(1, v/t))
+		if v >= t {
+			state = "over"
+		}
+	}
+	pct := strconv.FormatFloat(math.Round(ratio*10000)/100, 'f', -1, 64)
+
+	return template.HTML(`<div class="goal-bar ` + state + `" data-state="` + state +
+		`" role="progressbar" aria-valuemin="0" aria-valuemax="100" aria-valuenow="` + pct + `">` +
+		`<div class="goal-bar-fill" style="width: ` + pct + `%"></div></div>`)
+}
+
+// URL returns base with the given query parameters set. Parameters are
+// passed as alternating key/value pairs; values are converted with toString
+// and existing parameters with the same key are replaced. A trailing key
+// without a value is ignored. Returns base unchanged if it cannot be parsed.
+//
+// Usage in Go templates:
+//
+//	<a href="{{bf_url "/todos" "page" .NextPage "filter" .Filter}}">Next</a>
+func URL(base string, kv ...any) string {
+	u, err := url.Parse(base)
+	if err != nil {
+		return base
+	}
+	query := u.Query()
+	for i := 0; i+1 < len(kv); i += 2 {
+		query.Set(toString(kv[i]), toString(kv[i+1]))
+	}
+	u.RawQuery = query.Encode()
+	return u.String()
+}
+
+// SortHeader renders a sortable table header cell linking to baseURL with
+// sort/dir query parameters (built with URL). When field is the current sort
+// column the link toggles the direction and aria-sort reflects the current
+// direction; otherwise the link sorts ascending and aria-sort is "none".
+func SortHeader(label, field, currentField, currentDir, baseURL string) template.HTML {
+	ariaSort, nextDir := "none", "asc"
+	if field == currentField {
+		if currentDir == "desc" {
+			ariaSort = "descending"
+		} else {
+			ariaSort, nextDir = "ascending", "desc"
+		}
+	}
+
+	href := URL(baseURL, "sort", field, "dir", nextDir)
+	return template.HTML(`<th aria-sort="` + ariaSort + `" data-sort-field="` + template.HTMLEscapeString(field) + `">` +
+		`<a href="` + template.HTMLEscapeString(href) + `">` + template.HTMLEscapeString(label) + `</a></th>`)
+}
+
+// EmptyState renders an empty-state block with an escaped title and message,
+// following the Empty UI component's data-slot structure. action (e.g., a
+// button) is trusted HTML placed in an empty-content slot, which is omitted
+// when action is empty.
+func EmptyState(title, message string, action template.HTML) template.HTML {
+	var buf strings.Builder
+	buf.WriteString(`<div data-slot="empty" class="text-center"><div data-slot="empty-header">`)
+	buf.WriteString(`<div data-slot="empty-title">`)
+	buf.WriteString(template.HTMLEscapeString(title))
+	buf.WriteString(`</div><div data-slot="empty-description">`)
+	buf.WriteString(template.HTMLEscapeString(message))
+	buf.WriteString(`</div></div>`)
+	if action != "" {
+		buf.WriteString(`<div data-slot="empty-content">`)
+		buf.WriteString(string(action))
+		buf.WriteString(`</div>`)
+	}
+	buf.WriteString(`</div>`)
+	return template.HTML(buf.String())
+}
@@ -0,0 +1,460 @@
+package bf
+
+import (
+	"html/template"
+	"strings"
+	"testing"
+)
+
+func TestDetails_Closed(t *testing.T) {
+	got := Details("Question", template.HTML("<p>Answer</p>"), false)
+	want := template.HTML("<details><summary>Question</summary><p>Answer</p></details>")
+	if got != want {
+		t.Errorf("Details closed = %q, want %q", got, want)
+	}
+}
+
+func TestDetails_Open(t *testing.T) {
+	got := Details("Q & A", template.HTML("<p>Answer</p>"), true)
+	want := template.HTML("<details open><summary>Q &amp; A</summary><p>Answer</p></details>")
+	if got != want {
+		t.Errorf("Details open = %q, want %q", got, want)
+	}
+}
+
+func TestTabs_ActiveTab(t *testing.T) {
+	tabs := []Tab{
+		{ID: "account", Label: "Account", Content: "<p>Account settings</p>"},
+		{ID: "password", Label: "Password", Content: "<p>Change password</p>"},
+	}
+
+	got := string(Tabs("settings", tabs, "password"))
+
+	checks := []string{
+		`<button type="button" data-slot="tabs-trigger" role="tab" id="settings-tab-account" aria-controls="settings-panel-account" aria-selected="false" data-state="inactive" tabindex="-1" data-value="account">Account</button>`,
+		`<button type="button" data-slot="tabs-trigger" role="tab" id="settings-tab-password" aria-controls="settings-panel-password" aria-selected="true" data-state="active" tabindex="0" data-value="password">Password</button>`,
+		`<div data-slot="tabs-content" role="tabpanel" id="settings-panel-account" aria-labelledby="settings-tab-account" data-state="inactive" hidden data-value="account" tabindex="0"><p>Account settings</p></div>`,
+		`<div data-slot="tabs-content" role="tabpanel" id="settings-panel-password" aria-labelledby="settings-tab-password" data-state="active" data-value="password" tabindex="0"><p>Change password</p></div>`,
+	}
+	for _, want := range checks {
+		if !contains(got, want) {
+			t.Errorf("Tabs output missing %q\ngot: %s", want, got)
+		}
+	}
+}
+
+func TestTabs_EscapesLabel(t *testing.T) {
+	got := string(Tabs("g", []Tab{{ID: "a", Label: "<b>A</b>"}}, "a"))
+	if !contains(got, "&lt;b&gt;A&lt;/b&gt;") {
+		t.Errorf("Tabs should escape labels, got %s", got)
+	}
+}
+
+func TestTabs_GroupScopesIDs(t *testing.T) {
+	tabs := []Tab{{ID: "info", Label: "Info"}}
+	a := string(Tabs("order-1", tabs, "info"))
+	b := string(Tabs("order-2", tabs, "info"))
+	for _, want := range []string{`id="order-1-tab-info"`, `id="order-1-panel-info"`} {
+		if !contains(a, want) {
+			t.Errorf("Tabs output missing %q\ngot: %s", want, a)
+		}
+	}
+	if contains(b, `id="order-1-`) {
+		t.Errorf("second tab group reuses first group's ids: %s", b)
+	}
+}
+
+func TestStepper(t *testing.T) {
+	steps := []string{"Cart", "Shipping", "Payment"}
+
+	tests := []struct {
+		name    string
+		current int
+		want    string
+	}{
+		{
+			name:    "first",
+			current: 0,
+			want: `<ol data-slot="stepper">` +
+				`<li class="active" data-state="active" aria-current="step" aria-label="Step 1 of 3: Cart (current)">Cart</li>` +
+				`<li class="upcoming" data-state="upcoming" aria-label="Step 2 of 3: Shipping (upcoming)">Shipping</li>` +
+				`<li class="upcoming" data-state="upcoming" aria-label="Step 3 of 3: Payment (upcoming)">Payment</li>` +
+				`</ol>`,
+		},
+		{
+			name:    "middle",
+			current: 1,
+			want: `<ol data-slot="stepper">` +
+				`<li class="done" data-state="done" aria-label="Step 1 of 3: Cart (completed)">Cart</li>` +
+				`<li class="active" data-state="active" aria-current="step" aria-label="Step 2 of 3: Shipping (current)">Shipping</li>` +
+				`<li class="upcoming" data-state="upcoming" aria-label="Step 3 of 3: Payment (upcoming)">Payment</li>` +
+				`</ol>`,
+		},
+		{
+			name:    "last",
+			current: 2,
+			want: `<ol data-slot="stepper">` +
+				`<li class="done" data-state="done" aria-label="Step 1 of 3: Cart (completed)">Cart</li>` +
+				`<li class="done" data-state="done" aria-label="Step 2 of 3: Shipping (completed)">Shipping</li>` +
+				`<li class="active" data-state="active" aria-current="step" aria-label="Step 3 of 3: Payment (current)">Payment</li>` +
+				`</ol>`,
+		},
+	}
+
+	for _, tt := range tests {
+		got := string(Stepper(steps, tt.current))
+		if got != tt.want {
+			t.Errorf("Stepper %s = %q, want %q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestStepper_ClampsCurrent(t *testing.T) {
+	steps := []string{"A", "B"}
+	if got, want := Stepper(steps, 99), Stepper(steps, 1); got != want {
+		t.Errorf("Stepper(99) = %q, want clamped to last step %q", got, want)
+	}
+	if got, want := Stepper(steps, -1), Stepper(steps, 0); got != want {
+		t.Errorf("Stepper(-1) = %q, want clamped to first step %q", got, want)
+	}
+}
+
+func TestToastRegion(t *testing.T) {
+	toasts := []Toast{
+		{ID: "t1", Variant: "success", Message: "Saved"},
+		{ID: "t2", Variant: "error", Message: "<script>x</script>"},
+		{ID: "t3", Message: "Plain"},
+	}
+
+	got := string(ToastRegion(toasts))
+
+	checks := []string{
+		`<div data-slot="toast-provider" role="region" aria-label="Notifications" aria-live="polite">`,
+		`<div data-slot="toast" role="status" bf-toast-id="t1" data-variant="success" class="toast toast-success">Saved</div>`,
+		`<div data-slot="toast" role="status" bf-toast-id="t2" data-variant="error" class="toast toast-error">&lt;script&gt;x&lt;/script&gt;</div>`,
+		`<div data-slot="toast" role="status" bf-toast-id="t3" data-variant="default" class="toast toast-default">Plain</div>`,
+	}
+	for _, want := range checks {
+		if !contains(got, want) {
+			t.Errorf("ToastRegion output missing %q\ngot: %s", want, got)
+		}
+	}
+}
+
+func TestToastRegion_ViaPortalCollector(t *testing.T) {
+	tmpl := template.Must(template.New("page").Funcs(FuncMap()).Parse(
+		`<main>{{.Portals.Add .ScopeID (bf_toasts .Toasts)}}</main>`,
+	))
+	data := struct {
+		ScopeID string
+		Portals *PortalCollector
+		Toasts  []Toast
+	}{
+		ScopeID: "App_1",
+		Portals: NewPortalCollector(),
+		Toasts:  []Toast{{ID: "t1", Variant: "info", Message: "Hello"}},
+	}
+
+	var buf strings.Builder
+	if err := tmpl.Execute(&buf, data); err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if buf.String() != "<main></main>" {
+		t.Errorf("toasts should not render inline, got %q", buf.String())
+	}
+
+	portals := string(data.Portals.Render())
+	if !contains(portals, `bf-po="App_1"`) || !contains(portals, `bf-toast-id="t1"`) {
+		t.Errorf("toasts should render via portals, got %q", portals)
+	}
+}
+
+func TestRatingInput(t *testing.T) {
+	got := string(RatingInput("score", 2, 3))
+	want := `<div role="radiogroup" bf-rating="score" data-value="2">` +
+		`<input type="radio" name="score" value="1" bf-rating-star="1" data-state="on" aria-label="1 star">` +
+		`<input type="radio" name="score" value="2" bf-rating-star="2" data-state="on" aria-label="2 stars" checked>` +
+		`<input type="radio" name="score" value="3" bf-rating-star="3" data-state="off" aria-label="3 stars">` +
+		`</div>`
+	if got != want {
+		t.Errorf("RatingInput(score, 2, 3) =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestRatingInput_NoSelection(t *testing.T) {
+	got := string(RatingInput("score", 0, 5))
+	if contains(got, "checked") {
+		t.Errorf("RatingInput with value 0 should not pre-check a star, got %s", got)
+	}
+	if !contains(got, `bf-rating-star="5"`) {
+		t.Errorf("RatingInput should render max stars, got %s", got)
+	}
+}
+
+func TestRatingInput_ClampsValue(t *testing.T) {
+	if got, want := RatingInput("r", 9, 3), RatingInput("r", 3, 3); got != want {
+		t.Errorf("RatingInput(9, 3) = %q, want clamped %q", got, want)
+	}
+}
+
+func TestLoadMoreSentinel(t *testing.T) {
+	got := LoadMoreSentinel("/items?page=2&sort=new")
+	want := template.HTML(`<div bf-load-more data-next="/items?page=2&amp;sort=new"></div>`)
+	if got != want {
+		t.Errorf("LoadMoreSentinel = %q, want %q", got, want)
+	}
+
+	if got := LoadMoreSentinel(""); got != "" {
+		t.Errorf("LoadMoreSentinel(\"\") = %q, want empty", got)
+	}
+}
+
+func TestIconButton_KnownIcon(t *testing.T) {
+	RegisterIcon("test-close", `<svg viewBox="0 0 24 24"><path d="M6 6l12 12"/></svg>`)
+
+	got := string(IconButton("test-close", "Close \"dialog\"", map[string]string{
+		"class":     "icon-btn",
+		"data-slot": "dialog-close",
+	}))
+	want := `<button class="icon-btn" data-slot="dialog-close" type="button" aria-label="Close &#34;dialog&#34;">` +
+		`<svg viewBox="0 0 24 24"><path d="M6 6l12 12"/></svg></button>`
+	if got != want {
+		t.Errorf("IconButton =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestIconButton_OverridesAndUnknownIcon(t *testing.T) {
+	got := string(IconButton("no-such-icon", "Submit", map[string]string{
+		"type":       "submit",
+		"aria-label": "ignored",
+	}))
+	want := `<button type="submit" aria-label="Submit">` +
+		`<span data-icon-placeholder="no-such-icon" aria-hidden="true"></span></button>`
+	if got != want {
+		t.Errorf("IconButton =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestIconButton_SkipsInvalidAttrNames(t *testing.T) {
+	got := string(IconButton("no-such-icon", "Close", map[string]string{
+		"x onmouseover=alert(1) y": "z",
+		`a"b`:                      "c",
+		"title":                    "Close",
+	}))
+	want := `<button title="Close" type="button" aria-label="Close">` +
+		`<span data-icon-placeholder="no-such-icon" aria-hidden="true"></span></button>`
+	if got != want {
+		t.Errorf("IconButton =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestOverlayTheme(t *testing.T) {
+	tests := []struct {
+		hex  string
+		want string
+	}{
+		{"#f5f0e6", "on-light"},
+		{"#fff", "on-light"},
+		{"#1a1a2e", "on-dark"},
+		{"#000000", "on-dark"},
+		{"not-a-color", "on-dark"},
+		{"#12345", "on-dark"},
+		{"", "on-dark"},
+	}
+
+	for _, tt := range tests {
+		if got := OverlayTheme(tt.hex); got != tt.want {
+			t.Errorf("OverlayTheme(%q) = %q, want %q", tt.hex, got, tt.want)
+		}
+	}
+}
+
+type checklistItem struct {
+	Title string
+	Done  bool
+}
+
+func TestChecklist(t *testing.T) {
+	const doneBox = `<span role="img" aria-label="completed">☑</span> `
+	const pendingBox = `<span role="img" aria-label="not completed">☐</span> `
+
+	tests := []struct {
+		name  string
+		items []checklistItem
+		want  string
+	}{
+		{
+			name:  "all done",
+			items: []checklistItem{{"Sign up", true}, {"Verify", true}},
+			want: `<ul data-slot="checklist">` +
+				`<li data-state="done">` + doneBox + `Sign up</li>` +
+				`<li data-state="done">` + doneBox + `Verify</li></ul>`,
+		},
+		{
+			name:  "none done",
+			items: []checklistItem{{"Sign up", false}, {"Verify", false}},
+			want: `<ul data-slot="checklist">` +
+				`<li data-state="pending">` + pendingBox + `Sign up</li>` +
+				`<li data-state="pending">` + pendingBox + `Verify</li></ul>`,
+		},
+		{
+			name:  "mixed",
+			items: []checklistItem{{"Sign up", true}, {"Invite <team>", false}},
+			want: `<ul data-slot="checklist">` +
+				`<li data-state="done">` + doneBox + `Sign up</li>` +
+				`<li data-state="pending">` + pendingBox + `Invite &lt;team&gt;</li></ul>`,
+		},
+	}
+
+	for _, tt := range tests {
+		got := string(Checklist(tt.items, "title", "done"))
+		if got != tt.want {
+			t.Errorf("Checklist %s =\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestImgWithFallback(t *testing.T) {
+	got := ImgWithFallback("/avatars/42.png?s=64&r=g", "Ada's avatar", "/img/placeholder.png")
+	want := template.HTML(`<img src="/avatars/42.png?s=64&amp;r=g" alt="Ada&#39;s avatar" data-bf-fallback="/img/placeholder.png">`)
+	if got != want {
+		t.Errorf("ImgWithFallback = %q, want %q", got, want)
+	}
+}
+
+func TestImgWithFallback_EmptySrc(t *testing.T) {
+	got := ImgWithFallback("", "Avatar", "/img/placeholder.png")
+	want := template.HTML(`<img src="/img/placeholder.png" alt="Avatar">`)
+	if got != want {
+		t.Errorf("ImgWithFallback empty src = %q, want %q", got, want)
+	}
+}
+
+func TestSegmented_Active(t *testing.T) {
+	options := []SegmentOption{
+		{Value: "day", Label: "Day"},
+		{Value: "week", Label: "Week"},
+	}
+
+	got := string(Segmented(options, "week"))
+	want := `<div data-slot="toggle-group" role="group">` +
+		`<button type="button" data-slot="toggle-group-item" data-state="off" aria-pressed="false" data-value="day">Day</button>` +
+		`<button type="button" data-slot="toggle-group-item" class="active" data-state="on" aria-pressed="true" data-value="week">Week</button>` +
+		`</div>`
+	if got != want {
+		t.Errorf("Segmented =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestSegmented_UnknownActive(t *testing.T) {
+	options := []SegmentOption{
+		{Value: "a", Label: "A & B"},
+		{Value: "b", Label: "B"},
+	}
+
+	got := string(Segmented(options, "missing"))
+	if contains(got, `aria-pressed="true"`) || contains(got, `class="active"`) {
+		t.Errorf("Segmented with unknown active should mark nothing, got %s", got)
+	}
+	if !contains(got, "A &amp; B") {
+		t.Errorf("Segmented should escape labels, got %s", got)
+	}
+}
+
+func TestGoalBar(t *testing.T) {
+	tests := []struct {
+		name          string
+		value, target any
+		state, pct    string
+	}{
+		{"under target", 25, 100, "under", "25"},
+		{"at target", 100, 100.0, "over", "100"},
+		{"over target", 150.5, 100, "over", "100"},
+		{"zero target", 10, 0, "under", "0"},
+		{"fractional", 1, 3, "under", "33.33"},
+	}
+
+	for _, tt := range tests {
+		got := string(GoalBar(tt.value, tt.target))
+		wantClass := `class="goal-bar ` + tt.state + `"`
+		wantWidth := `style="width: ` + tt.pct + `%"`
+		if !contains(got, wantClass) || !contains(got, wantWidth) {
+			t.Errorf("GoalBar %s = %s, want %s and %s", tt.name, got, wantClass, wantWidth)
+		}
+	}
+}
+
+func TestURL(t *testing.T) {
+	tests := []struct {
+		base string
+		kv   []any
+		want string
+	}{
+		{"/todos", []any{"page", 2}, "/todos?page=2"},
+		{"/todos?filter=done&page=1", []any{"page", 3}, "/todos?filter=done&page=3"},
+		{"/search", []any{"q", "a&b c"}, "/search?q=a%26b+c"},
+		{"/todos", []any{"dangling"}, "/todos"},
+	}
+
+	for _, tt := range tests {
+		if got := URL(tt.base, tt.kv...); got != tt.want {
+			t.Errorf("URL(%q, %v) = %q, want %q", tt.base, tt.kv, got, tt.want)
+		}
+	}
+}
+
+func TestSortHeader(t *testing.T) {
+	tests := []struct {
+		name         string
+		currentField string
+		currentDir   string
+		want         string
+	}{
+		{
+			name:         "active asc",
+			currentField: "name",
+			currentDir:   "asc",
+			want:         `<th aria-sort="ascending" data-sort-field="name"><a href="/users?dir=desc&amp;sort=name">Name</a></th>`,
+		},
+		{
+			name:         "active desc",
+			currentField: "name",
+			currentDir:   "desc",
+			want:         `<th aria-sort="descending" data-sort-field="name"><a href="/users?dir=asc&amp;sort=name">Name</a></th>`,
+		},
+		{
+			name:         "inactive",
+			currentField: "email",
+			currentDir:   "desc",
+			want:         `<th aria-sort="none" data-sort-field="name"><a href="/users?dir=asc&amp;sort=name">Name</a></th>`,
+		},
+	}
+
+	for _, tt := range tests {
+		got := string(SortHeader("Name", "name", tt.currentField, tt.currentDir, "/users"))
+		if got != tt.want {
+			t.Errorf("SortHeader %s =\n%q\nwant\n%q", tt.name, got, tt.want)
+		}
+	}
+}
+
+func TestEmptyState_WithAction(t *testing.T) {
+	got := string(EmptyState("No todos", "Add one to <get> started", `<button>New todo</button>`))
+	want := `<div data-slot="empty" class="text-center"><div data-slot="empty-header">` +
+		`<div data-slot="empty-title">No todos</div>` +
+		`<div data-slot="empty-description">Add one to &lt;get&gt; started</div></div>` +
+		`<div data-slot="empty-content"><button>New todo</button></div></div>`
+	if got != want {
+		t.Errorf("EmptyState with action =\n%q\nwant\n%q", got, want)
+	}
+}
+
+func TestEmptyState_WithoutAction(t *testing.T) {
+	got := string(EmptyState("No results", "Try another search", ""))
+	if contains(got, "empty-content") {
+		t.Errorf("EmptyState without action should omit empty-content, got %s", got)
+	}
+	if !contains(got, `<div data-slot="empty-title">No results</div>`) {
+		t.Errorf("EmptyState should render title, got %s", got)
+	}
+}